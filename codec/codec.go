@@ -0,0 +1,124 @@
+package codec
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/protos"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Run runs cfg.Command, writing its result to w.
+// If the bytes to decode are not passed as an arg, they are read from r.
+func Run(cfg *config.Config, p *protos.Protos, r io.Reader, w io.Writer) error {
+	cmd := cfg.Command
+	switch cmd.Name {
+	case config.CommandEncode:
+		m, ok := cfg.InMessages[cmd.Args[0]]
+		if !ok {
+			return fmt.Errorf("unknown in-message alias: %q", cmd.Args[0])
+		}
+		b, err := Encode(p, m, cmd.Args[1:])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, formatBytes(b, cmd.Encoding))
+		return nil
+	case config.CommandDecode:
+		var s string
+		if len(cmd.Args) > 1 {
+			s = cmd.Args[1]
+		} else {
+			in, err := io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("could not read bytes: %w", err)
+			}
+			s = string(in)
+		}
+		b, err := parseBytes(s, cmd.Encoding)
+		if err != nil {
+			return fmt.Errorf("could not parse %s bytes: %w", cmd.Encoding, err)
+		}
+		res, err := Decode(cfg, p, cmd.Args[0], b)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, res)
+		return nil
+	default:
+		return fmt.Errorf("unknown command: %q", cmd.Name) // config has already been validated
+	}
+}
+
+// Encode encodes the in-message m built from args.
+//
+// Args are the values of m's params. JSON values, e.g., 10, true, "foo", [1, 2], are used as is;
+// single-quoted strings are unquoted the way they are in queries, e.g., 'O\'Reilly' -> "O'Reilly";
+// anything else is treated as a string, e.g., foo -> "foo".
+func Encode(p *protos.Protos, m *config.InMessage, args []string) ([]byte, error) {
+	jsonArgs := make([]string, 0, len(args))
+	for _, arg := range args {
+		jsonArgs = append(jsonArgs, jsonArg(arg))
+	}
+	jsonMessage, err := m.JSON(jsonArgs)
+	if err != nil {
+		return nil, err
+	}
+	return p.ProtoBytes(m.Name, jsonMessage)
+}
+
+// Decode decodes b as the message specified by name, which is one of, in order of precedence:
+// an out-message alias, rendered the way query results are, an in-message alias, or a message full name,
+// the latter two rendered as protojson.
+func Decode(cfg *config.Config, p *protos.Protos, name string, b []byte) (string, error) {
+	if om, ok := cfg.OutMessages[name]; ok {
+		stringer, err := p.StringerFor(om)
+		if err != nil {
+			return "", err
+		}
+		res, err := stringer(b)
+		if err != nil {
+			return "", err
+		}
+		return res.String(), nil
+	}
+	message := protoreflect.FullName(name)
+	if im, ok := cfg.InMessages[name]; ok {
+		message = im.Name
+	}
+	return p.ProtoJSON(message, b)
+}
+
+func jsonArg(arg string) string {
+	if json.Valid([]byte(arg)) {
+		return arg
+	}
+	if len(arg) > 1 && strings.HasPrefix(arg, "'") && strings.HasSuffix(arg, "'") {
+		arg = strings.ReplaceAll(arg[1:len(arg)-1], `\'`, "'")
+	}
+	res, _ := json.Marshal(arg) // a string cannot fail to marshal
+	return string(res)
+}
+
+func formatBytes(b []byte, encoding string) string {
+	if encoding == config.EncodingBase64 {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return hex.EncodeToString(b)
+}
+
+// parseBytes parses s, ignoring surrounding whitespace, and, for hex, a \x (as printed by psql) or 0x prefix.
+func parseBytes(s, encoding string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if encoding == config.EncodingBase64 {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	s = strings.TrimPrefix(s, `\x`)
+	s = strings.TrimPrefix(s, "0x")
+	return hex.DecodeString(s)
+}
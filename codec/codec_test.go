@@ -0,0 +1,128 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/cpb/internal/testconfig"
+	"github.com/m18/cpb/internal/testproto"
+	"github.com/m18/cpb/protos"
+)
+
+func TestRun(t *testing.T) {
+	cfg, err := testconfig.MakeTestConfigLite("postgres")
+	testcheck.FatalIf(t, err)
+	p, err := protos.New(
+		&config.Proto{C: config.ProtocBuiltin, Dir: testproto.DirLite, Deterministic: true},
+		testproto.MakeFS,
+		testproto.MakeFileReg(),
+		testproto.Mute,
+	)
+	testcheck.FatalIf(t, err)
+	tests := []struct {
+		desc     string
+		cmd      *config.Command
+		in       string
+		expected string
+		err      bool
+	}{
+		{
+			desc:     "encode, hex",
+			cmd:      &config.Command{Name: config.CommandEncode, Args: []string{"foo", "1", "a", "true"}, Encoding: config.EncodingHex},
+			expected: "08011201611801",
+		},
+		{
+			desc:     "encode, base64, quoted string",
+			cmd:      &config.Command{Name: config.CommandEncode, Args: []string{"foo", "1", `'O\'Reilly'`, "true"}, Encoding: config.EncodingBase64},
+			expected: "CAESCE8nUmVpbGx5GAE=",
+		},
+		{
+			desc: "encode, unknown alias",
+			cmd:  &config.Command{Name: config.CommandEncode, Args: []string{"unknown"}, Encoding: config.EncodingHex},
+			err:  true,
+		},
+		{
+			desc: "encode, wrong arg count",
+			cmd:  &config.Command{Name: config.CommandEncode, Args: []string{"foo", "1"}, Encoding: config.EncodingHex},
+			err:  true,
+		},
+		{
+			desc:     "decode, out-message alias",
+			cmd:      &config.Command{Name: config.CommandDecode, Args: []string{"qux", "0801"}, Encoding: config.EncodingHex},
+			expected: "1 (1): <no value>",
+		},
+		{
+			desc:     "decode, in-message alias, psql hex",
+			cmd:      &config.Command{Name: config.CommandDecode, Args: []string{"foo", `\x08011201611801`}, Encoding: config.EncodingHex},
+			expected: `{"id":1,"text":"a","isOn":true}`,
+		},
+		{
+			desc:     "decode, message name, base64 from input",
+			cmd:      &config.Command{Name: config.CommandDecode, Args: []string{"testproto.lite.Foo"}, Encoding: config.EncodingBase64},
+			in:       " CAESAWEYAQ==\n",
+			expected: `{"id":1,"text":"a","isOn":true}`,
+		},
+		{
+			desc: "decode, unknown message",
+			cmd:  &config.Command{Name: config.CommandDecode, Args: []string{"unknown", "0801"}, Encoding: config.EncodingHex},
+			err:  true,
+		},
+		{
+			desc: "decode, invalid hex",
+			cmd:  &config.Command{Name: config.CommandDecode, Args: []string{"foo", "zz"}, Encoding: config.EncodingHex},
+			err:  true,
+		},
+		{
+			desc: "decode, invalid message bytes",
+			cmd:  &config.Command{Name: config.CommandDecode, Args: []string{"foo", "ff"}, Encoding: config.EncodingHex},
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			cfg := *cfg
+			cfg.Command = test.cmd
+			w := &bytes.Buffer{}
+			err := Run(&cfg, p, strings.NewReader(test.in), w)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if res := strings.TrimSuffix(w.String(), "\n"); res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestJSONArg(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected string
+	}{
+		{arg: "10", expected: "10"},
+		{arg: "true", expected: "true"},
+		{arg: `"foo"`, expected: `"foo"`},
+		{arg: "[1, 2]", expected: "[1, 2]"},
+		{arg: "foo", expected: `"foo"`},
+		{arg: "'foo bar'", expected: `"foo bar"`},
+		{arg: `'O\'Reilly'`, expected: `"O'Reilly"`},
+		{arg: `a "quoted" word`, expected: `"a \"quoted\" word"`},
+		{arg: "'", expected: `"'"`},
+		{arg: "", expected: `""`},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.arg, func(t *testing.T) {
+			t.Parallel()
+			if res := jsonArg(test.arg); res != test.expected {
+				t.Fatalf("expected %s but got %s", test.expected, res)
+			}
+		})
+	}
+}
@@ -2,20 +2,32 @@ package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
+	"strings"
 	"text/template"
 
+	"github.com/m18/cpb/internal/glob"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 const (
 	defaultConfigFileName = "config.json"
 	defaultProtoc         = "protoc"
+	defaultFormat         = "table"
+	defaultSpacing        = 1
+	passwordMask          = "********"
+
+	driverSQLite = "sqlite" // see db.DriverSQLite
 
 	flagProtoc          = "c"
 	flagProtoDir        = "b"
+	flagImportPath      = "I"
+	flagDescriptorSet   = "x"
+	flagInclude         = "include"
+	flagExclude         = "exclude"
 	flagUndeterministic = "D"
 	flagNoAutoMap       = "M"
 	flagDriver          = "d"
@@ -24,37 +36,99 @@ const (
 	flagName            = "n"
 	flagUserName        = "u"
 	flagPassword        = "w"
+	flagFormat          = "o"
+	flagNoHeader        = "H"
+	flagSpacing         = "g"
+	flagScriptFile      = "i"
+	flagOnError         = "on-error"
+	flagSingleTx        = "single-transaction"
+	flagDryRun          = "dry-run"
+	flagHex             = "hex"
+	flagBase64          = "base64"
+	flagProfile         = "P"
 
 	FlagFile = "f"
+
+	ProtocBuiltin = "builtin"
+
+	OutMessageFormatJSON = "json"
+	OutMessageFormatText = "text"
+
+	OnErrorStop     = "stop"
+	OnErrorContinue = "continue"
+
+	CommandEncode = "encode"
+	CommandDecode = "decode"
+
+	EncodingHex    = "hex"
+	EncodingBase64 = "base64"
 )
 
+// defaultConfigFileNames are looked up in order when no config file is specified.
+var defaultConfigFileNames = []string{defaultConfigFileName, "config.yaml", "config.yml", "config.toml"}
+
 // Config is application configuration.
 type Config struct {
-	Proto *Proto
-	DB    *DBConfig
+	Proto   *Proto
+	Profile string // the name of the profile DB has been overridden with, if any
+	DB      *DBConfig
+	Output  *Output
+	Script  *Script
+
+	Command *Command // nil unless an offline subcommand is run instead of queries
 
 	InMessages         map[string]*InMessage
 	OutMessages        map[string]*OutMessage
 	AutoMapOutMessages bool
+
+	messages *messagesConfig // as configured, for JSON
 }
 
 // Proto encapsulates protobuf-specific configuration.
 type Proto struct {
-	C             string `json:"c"`
-	Dir           string `json:"dir"` // TODO: multiple dirs
-	Deterministic bool   `json:"deterministic"`
+	C              string   `json:"c" env:"CPB_PROTOC"`
+	Dir            string   `json:"dir" env:"CPB_PROTO_DIR"`
+	ImportPaths    []string `json:"importPaths" env:"CPB_PROTO_IMPORT_PATHS"` // additional roots, searched after Dir
+	Include        []string `json:"include" env:"CPB_PROTO_INCLUDE"`          // globs of files to compile, relative to their roots; all files if empty
+	Exclude        []string `json:"exclude" env:"CPB_PROTO_EXCLUDE"`          // globs of files not to compile, relative to their roots
+	DescriptorSets []string `json:"descriptorSet" env:"CPB_PROTO_DESCRIPTOR_SETS"`
+	Deterministic  bool     `json:"deterministic" env:"CPB_PROTO_DETERMINISTIC"`
 }
 
 // DBConfig encapsulates database configuration.
 type DBConfig struct {
-	Driver   string            `json:"driver"`
-	Host     string            `json:"host"`
-	Port     int               `json:"port"`
-	Name     string            `json:"name"`
-	UserName string            `json:"userName"`
-	Password string            `json:"password"`
-	Params   map[string]string `json:"params"`
-	Query    string            `json:"query"`
+	Driver   string            `json:"driver" env:"CPB_DB_DRIVER"`
+	Host     string            `json:"host" env:"CPB_DB_HOST"`
+	Port     int               `json:"port" env:"CPB_DB_PORT"`
+	Name     string            `json:"name" env:"CPB_DB_NAME"`
+	UserName string            `json:"userName" env:"CPB_DB_USER_NAME"`
+	Password string            `json:"password" env:"CPB_DB_PASSWORD"`
+	Params   map[string]string `json:"params" env:"CPB_DB_PARAMS"` // merged item by item
+	Query    string            `json:"query,omitempty"`
+
+	PasswordCommand string `json:"passwordCommand,omitempty" env:"CPB_DB_PASSWORD_COMMAND"` // whose output is the password, unless it is set
+}
+
+// Output encapsulates query result output configuration.
+type Output struct {
+	Format  string `json:"format"`
+	Header  bool   `json:"header"`
+	Spacing int    `json:"spacing"`
+}
+
+// Script encapsulates configuration of running statements from a file or a pipe.
+type Script struct {
+	File              string `json:"file"`
+	OnError           string `json:"onError"`           // OnErrorStop or OnErrorContinue
+	SingleTransaction bool   `json:"singleTransaction"` // all statements, rolled back on the first error
+	DryRun            bool   `json:"dryRun"`            // print statements with their params instead of running them
+}
+
+// Command is an offline subcommand, which does not use the database, e.g., `cpb encode <alias> <args...>`.
+type Command struct {
+	Name     string // CommandEncode or CommandDecode
+	Args     []string
+	Encoding string // of the bytes being encoded or decoded: EncodingHex or EncodingBase64
 }
 
 // InMessage is configuration for "in" messages, that is, messages going to the database.
@@ -71,8 +145,9 @@ type OutMessage struct {
 	Alias string
 	Name  protoreflect.FullName
 
-	Template *template.Template
+	Template *template.Template  // nil if not defined
 	Props    map[string]struct{} // all dotProps defined in template
+	Format   string              // used to render entire messages when Template is nil: OutMessageFormatJSON or OutMessageFormatText
 }
 
 // New initializes and returns a new Config.
@@ -89,6 +164,50 @@ func New(args []string, makeFS func(string) fs.FS) (*Config, error) {
 	return res, nil
 }
 
+// JSON returns the effective configuration, i.e., the config file merged with the environment and the command line, with the password, the password command, and password-like params masked.
+func (c *Config) JSON() (string, error) {
+	db := *c.DB
+	db.Query = "" // not configuration, e.g., the very command printing it
+	if db.Password != "" {
+		db.Password = passwordMask
+	}
+	if db.PasswordCommand != "" {
+		db.PasswordCommand = passwordMask // may contain the password itself, e.g., `echo secret`
+	}
+	if db.Params != nil {
+		db.Params = make(map[string]string, len(c.DB.Params))
+		for k, v := range c.DB.Params {
+			if isSecretParam(k) {
+				v = passwordMask
+			}
+			db.Params[k] = v
+		}
+	}
+	messages := c.messages
+	if messages == nil {
+		messages = &messagesConfig{}
+	}
+	raw := &rawConfig{
+		Proto:    c.Proto,
+		Profile:  c.Profile, // but not the profiles, which may contain passwords
+		DB:       &db,
+		Output:   c.Output,
+		Script:   c.Script,
+		Messages: messages,
+	}
+	b, err := json.MarshalIndent(raw, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// isSecretParam reports whether the DB param k likely holds a password, e.g., `sslpassword` or `PWD`.
+func isSecretParam(k string) bool {
+	k = strings.ToLower(k)
+	return strings.Contains(k, "password") || strings.Contains(k, "pwd")
+}
+
 // JSON template.
 func (m *InMessage) JSON(args []string) (string, error) {
 	if len(args) != len(m.params) {
@@ -105,24 +224,92 @@ func (m *InMessage) JSON(args []string) (string, error) {
 	return buf.String(), nil
 }
 
+// Params returns the names of the alias parameters, in order.
+func (m *InMessage) Params() []string {
+	return append([]string(nil), m.params...)
+}
+
 func (c *Config) validate() error {
 	if c.Proto.C == "" {
 		c.Proto.C = defaultProtoc
 	}
+	if c.Proto.Dir == "" && len(c.Proto.ImportPaths) > 0 {
+		return errors.New("proto import paths require a proto dir")
+	}
+	for _, patterns := range [][]string{c.Proto.Include, c.Proto.Exclude} {
+		for _, pattern := range patterns {
+			if err := glob.Validate(pattern); err != nil {
+				return fmt.Errorf("invalid proto file glob %q: %w", pattern, err)
+			}
+		}
+	}
+	if c.Command != nil {
+		// offline subcommands do not need the database
+		return c.Command.validate()
+	}
 	if c.DB.Driver == "" {
 		return errors.New("driver is not specified")
 	}
-	if c.DB.Host == "" {
-		return errors.New("host is not specified")
+	if c.DB.Driver == driverSQLite {
+		// SQLite databases are files: no host, port, or user
+		if c.DB.Name == "" {
+			return errors.New("database file path is not specified")
+		}
+	} else {
+		if c.DB.Host == "" {
+			return errors.New("host is not specified")
+		}
+		if c.DB.Port == 0 {
+			return errors.New("port is not specified")
+		}
+		if c.DB.Name == "" {
+			return errors.New("database name is not specified")
+		}
+		if c.DB.UserName == "" {
+			return errors.New("user name is not specified")
+		}
+	}
+	if c.Output.Format == "" {
+		c.Output.Format = defaultFormat
+	}
+	if c.Output.Spacing < 0 {
+		return errors.New("spacing cannot be negative")
 	}
-	if c.DB.Port == 0 {
-		return errors.New("port is not specified")
+	switch c.Script.OnError {
+	case "":
+		c.Script.OnError = OnErrorStop
+	case OnErrorStop, OnErrorContinue:
+	default:
+		return fmt.Errorf("invalid on-error policy: %q", c.Script.OnError)
 	}
-	if c.DB.Name == "" {
-		return errors.New("database name is not specified")
+	if c.Script.SingleTransaction && c.Script.OnError == OnErrorContinue {
+		return errors.New("a single transaction cannot continue on error")
+	}
+	return nil
+}
+
+func (c *Command) validate() error {
+	switch c.Name {
+	case CommandEncode:
+		if len(c.Args) == 0 {
+			return errors.New("in-message alias is not specified")
+		}
+	case CommandDecode:
+		if len(c.Args) == 0 {
+			return errors.New("alias or message name is not specified")
+		}
+		if len(c.Args) > 2 {
+			return fmt.Errorf("too many arguments: %v", c.Args[2:])
+		}
+	default:
+		return fmt.Errorf("unknown command: %q", c.Name)
 	}
-	if c.DB.UserName == "" {
-		return errors.New("user name is not specified")
+	switch c.Encoding {
+	case "":
+		c.Encoding = EncodingHex
+	case EncodingHex, EncodingBase64:
+	default:
+		return fmt.Errorf("invalid encoding: %q", c.Encoding)
 	}
 	return nil
 }
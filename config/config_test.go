@@ -3,11 +3,13 @@ package config
 import (
 	"fmt"
 	"io/fs"
+	"strings"
 	"testing"
 	"text/template"
 
 	"github.com/m18/cpb/internal/testcheck"
 	"github.com/m18/cpb/internal/testfs"
+	"github.com/m18/eq"
 )
 
 func TestConfigNew(t *testing.T) {
@@ -71,6 +73,11 @@ func TestConfigValidate(t *testing.T) {
 				Name:     testExpectedName,
 				UserName: testExpectedUserName,
 			},
+			Output: &Output{
+				Format:  testExpectedFormat,
+				Spacing: testExpectedSpacing,
+			},
+			Script: &Script{},
 		}
 		if upd != nil {
 			upd(res)
@@ -89,6 +96,52 @@ func TestConfigValidate(t *testing.T) {
 			desc: "no protoc, default is used",
 			upd:  func(c *Config) { c.Proto.C = "" },
 		},
+		{
+			desc: "import paths",
+			upd: func(c *Config) {
+				c.Proto.Dir = "foo"
+				c.Proto.ImportPaths = []string{"bar", "baz"}
+			},
+		},
+		{
+			desc: "import paths without dir",
+			upd:  func(c *Config) { c.Proto.ImportPaths = []string{"bar"} },
+			err:  true,
+		},
+		{
+			desc: "valid globs",
+			upd: func(c *Config) {
+				c.Proto.Include = []string{"foo/**/*.proto"}
+				c.Proto.Exclude = []string{"foo/bar/*.proto"}
+			},
+		},
+		{
+			desc: "invalid include glob",
+			upd:  func(c *Config) { c.Proto.Include = []string{"["} },
+			err:  true,
+		},
+		{
+			desc: "invalid exclude glob",
+			upd:  func(c *Config) { c.Proto.Exclude = []string{"foo/["} },
+			err:  true,
+		},
+		{
+			desc: "sqlite, no host, port, or user",
+			upd: func(c *Config) {
+				c.DB.Driver = driverSQLite
+				c.DB.Host = ""
+				c.DB.Port = 0
+				c.DB.UserName = ""
+			},
+		},
+		{
+			desc: "sqlite, no file path",
+			upd: func(c *Config) {
+				c.DB.Driver = driverSQLite
+				c.DB.Name = ""
+			},
+			err: true,
+		},
 		{
 			desc: "no driver",
 			upd:  func(c *Config) { c.DB.Driver = "" },
@@ -114,6 +167,67 @@ func TestConfigValidate(t *testing.T) {
 			upd:  func(c *Config) { c.DB.UserName = "" },
 			err:  true,
 		},
+		{
+			desc: "no format, default is used",
+			upd:  func(c *Config) { c.Output.Format = "" },
+		},
+		{
+			desc: "zero spacing",
+			upd:  func(c *Config) { c.Output.Spacing = 0 },
+		},
+		{
+			desc: "negative spacing",
+			upd:  func(c *Config) { c.Output.Spacing = -1 },
+			err:  true,
+		},
+		{
+			desc: "on-error continue",
+			upd:  func(c *Config) { c.Script.OnError = OnErrorContinue },
+		},
+		{
+			desc: "single transaction",
+			upd:  func(c *Config) { c.Script.SingleTransaction = true },
+		},
+		{
+			desc: "single transaction, continue on error",
+			upd: func(c *Config) {
+				c.Script.SingleTransaction = true
+				c.Script.OnError = OnErrorContinue
+			},
+			err: true,
+		},
+		{
+			desc: "invalid on-error policy",
+			upd:  func(c *Config) { c.Script.OnError = "foo" },
+			err:  true,
+		},
+		{
+			desc: "command, no database",
+			upd: func(c *Config) {
+				c.DB = &DBConfig{}
+				c.Command = &Command{Name: CommandEncode, Args: []string{"foo"}}
+			},
+		},
+		{
+			desc: "encode, no alias",
+			upd:  func(c *Config) { c.Command = &Command{Name: CommandEncode} },
+			err:  true,
+		},
+		{
+			desc: "decode, too many args",
+			upd:  func(c *Config) { c.Command = &Command{Name: CommandDecode, Args: []string{"foo", "0a04", "0a04"}} },
+			err:  true,
+		},
+		{
+			desc: "unknown command",
+			upd:  func(c *Config) { c.Command = &Command{Name: "foo", Args: []string{"foo"}} },
+			err:  true,
+		},
+		{
+			desc: "invalid encoding",
+			upd:  func(c *Config) { c.Command = &Command{Name: CommandDecode, Args: []string{"foo"}, Encoding: "foo"} },
+			err:  true,
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -124,6 +238,84 @@ func TestConfigValidate(t *testing.T) {
 	}
 }
 
+func TestDBConfigRunPasswordCommand(t *testing.T) {
+	tests := []struct {
+		desc     string
+		db       *DBConfig
+		expected string
+		err      bool
+	}{
+		{
+			desc:     "command",
+			db:       &DBConfig{Driver: testExpectedDriver, PasswordCommand: "echo secret"},
+			expected: "secret",
+		},
+		{
+			desc: "no command",
+			db:   &DBConfig{Driver: testExpectedDriver},
+		},
+		{
+			desc:     "password takes precedence",
+			db:       &DBConfig{Driver: testExpectedDriver, Password: "pwd", PasswordCommand: "exit 1"},
+			expected: "pwd",
+		},
+		{
+			desc: "sqlite",
+			db:   &DBConfig{Driver: driverSQLite, PasswordCommand: "exit 1"},
+		},
+		{
+			desc: "failed command",
+			db:   &DBConfig{Driver: testExpectedDriver, PasswordCommand: "exit 1"},
+			err:  true,
+		},
+		{
+			desc: "empty output",
+			db:   &DBConfig{Driver: testExpectedDriver, PasswordCommand: "echo"},
+			err:  true,
+		},
+		{
+			desc: "no stdin when it is not a terminal",
+			db:   &DBConfig{Driver: testExpectedDriver, PasswordCommand: "cat"},
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			db := *test.db
+			err := db.RunPasswordCommand()
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if db.Password != test.expected {
+				t.Fatalf("expected password to be %q but it was %q", test.expected, db.Password)
+			}
+		})
+	}
+}
+
+func TestConfigValidateDoesNotRunPasswordCommand(t *testing.T) {
+	cfg := &Config{
+		Proto: &Proto{},
+		DB: &DBConfig{
+			Driver:          testExpectedDriver,
+			Host:            testExpectedHost,
+			Port:            testExpectedPort,
+			Name:            testExpectedName,
+			UserName:        testExpectedUserName,
+			PasswordCommand: "echo secret",
+		},
+		Output: &Output{},
+		Script: &Script{},
+	}
+	testcheck.FatalIf(t, cfg.validate())
+	if cfg.DB.Password != "" {
+		t.Fatalf("expected password to be empty but it was %q", cfg.DB.Password)
+	}
+}
+
 func TestInMessageJSON(t *testing.T) {
 	tpl0 := "hello, world! hi, cosmos."
 	tpl2 := "hello, {{.foo}}! hi, {{.bar}}."
@@ -188,3 +380,101 @@ func TestInMessageJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestInMessageParams(t *testing.T) {
+	im := &InMessage{params: []string{"foo", "bar"}}
+	res := im.Params()
+	if !eq.StringSlices(res, []string{"foo", "bar"}) {
+		t.Fatalf("expected %v but got %v", []string{"foo", "bar"}, res)
+	}
+	res[0] = "baz"
+	if im.params[0] != "foo" {
+		t.Fatalf("expected params to not be modifiable but they were")
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	testFS, testFileName := testfs.MakeTestConfigFS(testConfigJSON)
+	cfg, err := New([]string{"-" + FlagFile, testFileName, "-" + flagPort, "5501", testQuery}, func(string) fs.FS { return testFS })
+	testcheck.FatalIf(t, err)
+	res, err := cfg.JSON()
+	testcheck.FatalIf(t, err)
+	for _, s := range []string{`"port": 5501`, `"password": "` + passwordMask + `"`, `"foo()": {`, `"bar": {`} {
+		if !strings.Contains(res, s) {
+			t.Fatalf("expected %s to contain %s but it did not", res, s)
+		}
+	}
+	for _, s := range []string{testExpectedPassword, testQuery} {
+		if strings.Contains(res, s) {
+			t.Fatalf("expected %s to not contain %s but it did", res, s)
+		}
+	}
+	if cfg.DB.Password != testExpectedPassword {
+		t.Fatalf("expected password to be %q but it was %q", testExpectedPassword, cfg.DB.Password)
+	}
+}
+
+func TestConfigJSONMasksSecrets(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		desc       string
+		db         *DBConfig
+		masked     []string
+		unmasked   []string
+		unexpected []string
+	}{
+		{
+			desc:     "no secrets",
+			db:       &DBConfig{Params: map[string]string{"sslmode": "disable"}},
+			unmasked: []string{`"password": ""`, `"sslmode": "disable"`},
+		},
+		{
+			desc:       "password command",
+			db:         &DBConfig{PasswordCommand: "echo foo"},
+			masked:     []string{`"passwordCommand": "` + passwordMask + `"`},
+			unexpected: []string{"echo foo"},
+		},
+		{
+			desc: "password-like params",
+			db: &DBConfig{Params: map[string]string{
+				"sslpassword": "foo",
+				"PWD":         "bar",
+				"Password":    "baz",
+				"sslmode":     "disable",
+			}},
+			masked: []string{
+				`"sslpassword": "` + passwordMask + `"`,
+				`"PWD": "` + passwordMask + `"`,
+				`"Password": "` + passwordMask + `"`,
+			},
+			unmasked:   []string{`"sslmode": "disable"`},
+			unexpected: []string{"foo", "bar", "baz"},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			params := map[string]string{}
+			for k, v := range test.db.Params {
+				params[k] = v
+			}
+			cfg := &Config{DB: test.db}
+			res, err := cfg.JSON()
+			testcheck.FatalIf(t, err)
+			for _, s := range append(test.masked, test.unmasked...) {
+				if !strings.Contains(res, s) {
+					t.Fatalf("expected %s to contain %s but it did not", res, s)
+				}
+			}
+			for _, s := range test.unexpected {
+				if strings.Contains(res, s) {
+					t.Fatalf("expected %s to not contain %s but it did", res, s)
+				}
+			}
+			if !eq.StringMaps(cfg.DB.Params, params) {
+				t.Fatalf("expected params to stay %v but they were %v", params, cfg.DB.Params)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	envTag       = "env"
+	envSeparator = ","
+)
+
+// mergeEnv overrides the Proto and DB configuration with the environment variables named by the `env` tags of their fields.
+//
+// Lists are comma-separated, e.g., CPB_PROTO_INCLUDE=foo/*.proto,bar/*.proto;
+// maps are comma-separated key=value pairs, e.g., CPB_DB_PARAMS=sslmode=disable,connect_timeout=5, which are merged item by item.
+func (c *rawConfig) mergeEnv(lookupEnv func(string) (string, bool)) error {
+	for _, v := range []interface{}{c.Proto, c.DB} {
+		if err := mergeEnvFields(reflect.ValueOf(v).Elem(), lookupEnv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mergeEnvFields(v reflect.Value, lookupEnv func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup(envTag)
+		if !ok {
+			continue
+		}
+		s, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := mergeEnvField(v.Field(i).Addr().Interface(), s); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func mergeEnvField(target interface{}, s string) error {
+	switch target := target.(type) {
+	case *string:
+		*target = s
+	case *int:
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*target = v
+	case *bool:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*target = v
+	case *[]string:
+		*target = splitEnv(s)
+	case *map[string]string:
+		if *target == nil {
+			*target = map[string]string{}
+		}
+		for _, kv := range splitEnv(s) {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value but got %q", kv)
+			}
+			(*target)[k] = v
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", target) // a new field has been tagged
+	}
+	return nil
+}
+
+func splitEnv(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, envSeparator)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/cpb/internal/testfs"
+	"github.com/m18/eq"
+)
+
+func testLookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestRawConfigMergeEnv(t *testing.T) {
+	tests := []struct {
+		desc  string
+		env   map[string]string
+		check func(*rawConfig) error
+		err   bool
+	}{
+		{
+			desc: "no env",
+			check: func(c *rawConfig) error {
+				if c.DB.Host != "foo" {
+					return fmt.Errorf("expected host to be %q but it was %q", "foo", c.DB.Host)
+				}
+				return nil
+			},
+		},
+		{
+			desc: "all types",
+			env: map[string]string{
+				"CPB_PROTOC":              "bar",
+				"CPB_PROTO_INCLUDE":       "foo/*.proto,bar/*.proto",
+				"CPB_PROTO_EXCLUDE":       "",
+				"CPB_PROTO_DETERMINISTIC": "false",
+				"CPB_DB_HOST":             "bar",
+				"CPB_DB_PORT":             "5432",
+				"CPB_DB_PASSWORD":         "p=a,ss",
+				"CPB_DB_PARAMS":           "foo=baz,qux=a=b",
+			},
+			check: func(c *rawConfig) error {
+				if c.Proto.C != "bar" {
+					return fmt.Errorf("expected protoc to be %q but it was %q", "bar", c.Proto.C)
+				}
+				if expected := []string{"foo/*.proto", "bar/*.proto"}; !eq.StringSlices(c.Proto.Include, expected) {
+					return fmt.Errorf("expected include to be %v but it was %v", expected, c.Proto.Include)
+				}
+				if len(c.Proto.Exclude) != 0 {
+					return fmt.Errorf("expected exclude to be empty but it was %v", c.Proto.Exclude)
+				}
+				if c.Proto.Deterministic {
+					return fmt.Errorf("expected deterministic to be false but it was not")
+				}
+				if c.DB.Host != "bar" {
+					return fmt.Errorf("expected host to be %q but it was %q", "bar", c.DB.Host)
+				}
+				if c.DB.Port != 5432 {
+					return fmt.Errorf("expected port to be %d but it was %d", 5432, c.DB.Port)
+				}
+				if c.DB.Password != "p=a,ss" {
+					return fmt.Errorf("expected password to be %q but it was %q", "p=a,ss", c.DB.Password)
+				}
+				if c.DB.Name != "foo" {
+					return fmt.Errorf("expected name to be %q but it was %q", "foo", c.DB.Name)
+				}
+				expected := map[string]string{"foo": "baz", "bar": "foo", "qux": "a=b"}
+				if !eq.StringMaps(c.DB.Params, expected) {
+					return fmt.Errorf("expected params to be %v but they were %v", expected, c.DB.Params)
+				}
+				return nil
+			},
+		},
+		{
+			desc: "invalid int",
+			env:  map[string]string{"CPB_DB_PORT": "foo"},
+			err:  true,
+		},
+		{
+			desc: "invalid bool",
+			env:  map[string]string{"CPB_PROTO_DETERMINISTIC": "foo"},
+			err:  true,
+		},
+		{
+			desc: "invalid map",
+			env:  map[string]string{"CPB_DB_PARAMS": "foo=bar,baz"},
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			c := newRawConfig()
+			c.DB.Host = "foo"
+			c.DB.Name = "foo"
+			c.DB.Params = map[string]string{"foo": "bar", "bar": "foo"}
+			err := c.mergeEnv(testLookupEnv(test.env))
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			testcheck.FatalIf(t, test.check(c))
+		})
+	}
+}
+
+func TestParserParseEnv(t *testing.T) {
+	testFS, testFileName := testfs.MakeTestConfigFS(testConfigJSON)
+	p := newParser([]string{"-" + FlagFile, testFileName, "-" + flagHost, "flag"}, func(string) fs.FS { return testFS }, true)
+	p.lookupEnv = testLookupEnv(map[string]string{
+		"CPB_DB_HOST":     "env",
+		"CPB_DB_PASSWORD": "env",
+	})
+	cfg, err := p.parse()
+	testcheck.FatalIf(t, err)
+	if cfg.DB.Host != "flag" {
+		t.Fatalf("expected host to be %q but it was %q", "flag", cfg.DB.Host)
+	}
+	if cfg.DB.Password != "env" {
+		t.Fatalf("expected password to be %q but it was %q", "env", cfg.DB.Password)
+	}
+	if cfg.DB.UserName != testExpectedUserName {
+		t.Fatalf("expected user name to be %q but it was %q", testExpectedUserName, cfg.DB.UserName)
+	}
+}
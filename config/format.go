@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// toJSON converts b, the contents of the file at path, to JSON based on the file's extension: .yaml, .yml, or .toml.
+// Files with any other extension are expected to be JSON already.
+//
+// Values are decoded generically and marshaled back, so that, e.g., in-message templates keep their structure.
+func toJSON(path string, b []byte) ([]byte, error) {
+	var v interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		v = stringKeys(v)
+	case ".toml":
+		m := map[string]interface{}{}
+		if err := toml.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+		v = m
+	default:
+		return b, nil
+	}
+	return json.Marshal(v)
+}
+
+// stringKeys converts the keys of the maps in v to strings, recursively: yaml.v3 decodes mappings with non-string keys, e.g., 1: foo,
+// to map[interface{}]interface{}, which cannot be marshaled to JSON.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			res[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return res
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = stringKeys(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = stringKeys(val)
+		}
+	}
+	return v
+}
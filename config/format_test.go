@@ -0,0 +1,205 @@
+package config
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/m18/cpb/internal/testcheck"
+)
+
+const testFormatJSON = `{
+	"proto": {"dir": "proto", "importPaths": ["vendor"]},
+	"db": {"driver": "postgres", "host": "localhost", "port": 5432, "name": "cpb", "userName": "cpb", "params": {"sslmode": "disable"}},
+	"messages": {
+		"in": {
+			"e(name, rate, tags)": {
+				"name": "example.Employee",
+				"template": {"name": "$name", "hourly_rate": "$rate", "tags": "$tags", "phone": {"type": "WORK"}, "is_contractor": true}
+			}
+		},
+		"out": {
+			"e": {"name": "example.Employee", "template": "$name, rate: \\$$hourly_rate"}
+		}
+	}
+}`
+
+const testFormatYAML = `
+proto:
+  dir: proto
+  importPaths: [vendor]
+db:
+  driver: postgres
+  host: localhost
+  port: 5432
+  name: cpb
+  userName: cpb
+  params:
+    sslmode: disable
+messages:
+  in:
+    e(name, rate, tags):
+      name: example.Employee
+      template:
+        name: $name
+        hourly_rate: $rate
+        tags: $tags
+        phone:
+          type: WORK
+        is_contractor: true
+  out:
+    e:
+      name: example.Employee
+      template: '$name, rate: \$$hourly_rate'
+`
+
+const testFormatTOML = `
+[proto]
+dir = "proto"
+importPaths = ["vendor"]
+
+[db]
+driver = "postgres"
+host = "localhost"
+port = 5432
+name = "cpb"
+userName = "cpb"
+params = { sslmode = "disable" }
+
+[messages.in."e(name, rate, tags)"]
+name = "example.Employee"
+template = { name = "$name", hourly_rate = "$rate", tags = "$tags", phone = { type = "WORK" }, is_contractor = true }
+
+[messages.out.e]
+name = "example.Employee"
+template = '$name, rate: \$$hourly_rate'
+`
+
+func TestParserParseFormats(t *testing.T) {
+	parse := func(fileName, contents string) (*Config, error) {
+		fsys := fstest.MapFS{fileName: &fstest.MapFile{Data: []byte(contents)}}
+		return newParser([]string{"-" + FlagFile, fileName}, func(string) fs.FS { return fsys }, true).parse()
+	}
+	cfg, err := parse("config.json", testFormatJSON)
+	testcheck.FatalIf(t, err)
+	expected, err := cfg.JSON()
+	testcheck.FatalIf(t, err)
+	expectedIn, err := cfg.InMessages["e"].JSON([]string{`"foo"`, "1.5", `["bar"]`})
+	testcheck.FatalIf(t, err)
+	tests := []struct {
+		fileName string
+		contents string
+		err      bool
+	}{
+		{fileName: "config.yaml", contents: testFormatYAML},
+		{fileName: "config.YML", contents: testFormatYAML},
+		{fileName: "config.toml", contents: testFormatTOML},
+		{fileName: "config.yaml", contents: "db: [", err: true},
+		{fileName: "config.toml", contents: "db = ", err: true},
+		{fileName: "config.yaml", contents: testFormatJSON}, // JSON is YAML too
+		{fileName: "config.toml", contents: testFormatJSON, err: true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.fileName, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := parse(test.fileName, test.contents)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			res, err := cfg.JSON()
+			testcheck.FatalIf(t, err)
+			if res != expected {
+				t.Fatalf("expected\n%s\nbut got\n%s", expected, res)
+			}
+			in, err := cfg.InMessages["e"].JSON([]string{`"foo"`, "1.5", `["bar"]`})
+			testcheck.FatalIf(t, err)
+			if in != expectedIn {
+				t.Fatalf("expected in-message JSON to be %s but it was %s", expectedIn, in)
+			}
+		})
+	}
+}
+
+func TestParserDefaultFile(t *testing.T) {
+	tests := []struct {
+		desc     string
+		files    []string
+		expected string
+	}{
+		{desc: "none"},
+		{desc: "json first", files: []string{"config.toml", "config.json", "config.yaml"}, expected: "config.json"},
+		{desc: "yaml", files: []string{"config.yml", "config.toml"}, expected: "config.yml"},
+		{desc: "toml", files: []string{"config.toml"}, expected: "config.toml"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			fsys := fstest.MapFS{}
+			for _, name := range test.files {
+				fsys[name] = &fstest.MapFile{}
+			}
+			res, ok := newParser(nil, func(string) fs.FS { return fsys }, true).defaultFile()
+			if ok != (test.expected != "") || res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		desc     string
+		path     string
+		contents string
+		expected string
+		err      bool
+	}{
+		{
+			desc:     "json",
+			path:     "config.json",
+			contents: `{"a": 1}`,
+			expected: `{"a": 1}`,
+		},
+		{
+			desc:     "yaml",
+			path:     "config.yaml",
+			contents: "a: 1",
+			expected: `{"a":1}`,
+		},
+		{
+			desc:     "yaml, non-string keys",
+			path:     "config.yaml",
+			contents: "template:\n  names_by_id:\n    1: foo\n    2: bar\n  flags: {true: on}\n  list:\n    - 1.5: baz",
+			expected: `{"template":{"flags":{"true":"on"},"list":[{"1.5":"baz"}],"names_by_id":{"1":"foo","2":"bar"}}}`,
+		},
+		{
+			desc:     "yaml, invalid",
+			path:     "config.yml",
+			contents: "a: [",
+			err:      true,
+		},
+		{
+			desc:     "toml",
+			path:     "config.toml",
+			contents: "a = 1",
+			expected: `{"a":1}`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			res, err := toJSON(test.path, []byte(test.contents))
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if string(res) != test.expected {
+				t.Fatalf("expected %s but got %s", test.expected, res)
+			}
+		})
+	}
+}
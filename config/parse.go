@@ -5,24 +5,29 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 type parser struct {
-	args   []string
-	makeFS func(string) fs.FS
-	in     *inMessageParser
-	out    *outMessageParser
-	mute   bool
+	args      []string
+	makeFS    func(string) fs.FS
+	lookupEnv func(string) (string, bool)
+	in        *inMessageParser
+	out       *outMessageParser
+	mute      bool
 }
 
 func newParser(args []string, makeFS func(string) fs.FS, mute bool) *parser {
 	return &parser{
-		args:   args,
-		makeFS: makeFS,
-		in:     newInMessageParser(),
-		out:    newOutMessageParser(),
-		mute:   mute,
+		args:      args,
+		makeFS:    makeFS,
+		lookupEnv: os.LookupEnv,
+		in:        newInMessageParser(),
+		out:       newOutMessageParser(),
+		mute:      mute,
 	}
 }
 
@@ -35,6 +40,14 @@ func (p *parser) parse() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse file: %w", err)
 	}
+	// file < profile < env < flags
+	mergeString(&fileConfig.Profile, clConfig.Profile, isSet(flagProfile))
+	if err := fileConfig.applyProfile(); err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+	if err := fileConfig.mergeEnv(p.lookupEnv); err != nil {
+		return nil, fmt.Errorf("failed to parse environment: %w", err)
+	}
 	fileConfig.merge(clConfig, isSet)
 	return p.from(fileConfig)
 }
@@ -42,26 +55,46 @@ func (p *parser) parse() (*Config, error) {
 func (p *parser) parseCLArgs() (filePath string, flagsConfig *rawConfig, isSet func(string) bool, err error) {
 	flagsConfig = newRawConfig()
 	defaultSet := flag.NewFlagSet("config", flag.ContinueOnError)
-	defaultSet.StringVar(&filePath, FlagFile, "", fmt.Sprintf("Path to a config file to use. If not provided, an optional %q is assumed.", defaultConfigFileName))
-	defaultSet.StringVar(&flagsConfig.Proto.C, flagProtoc, "", fmt.Sprintf("Path to protoc. If not provided, %q is assumed.", defaultProtoc))
+	defaultSet.StringVar(&filePath, FlagFile, "", fmt.Sprintf("Path to a JSON, YAML (.yaml, .yml), or TOML (.toml) config file to use. If not provided, an optional %s is assumed.", strings.Join(defaultConfigFileNames, ", ")))
+	defaultSet.StringVar(&flagsConfig.Proto.C, flagProtoc, "", fmt.Sprintf("Path to protoc, or %q to compile .proto files in-process. If not provided, %q is assumed.", ProtocBuiltin, defaultProtoc))
 	defaultSet.StringVar(&flagsConfig.Proto.Dir, flagProtoDir, "", "Protobuf source root directory.")
-	defaultSet.StringVar(&flagsConfig.DB.Driver, flagDriver, "", "Database driver name. Possible values: postgres.")
+	defaultSet.Var((*stringsFlag)(&flagsConfig.Proto.ImportPaths), flagImportPath, "Additional protobuf source root directory, searched after the main one. Can be repeated.")
+	defaultSet.Var((*stringsFlag)(&flagsConfig.Proto.Include), flagInclude, "Glob of .proto files to compile, relative to their roots, e.g., \"mycompany/**/*.proto\". Can be repeated.")
+	defaultSet.Var((*stringsFlag)(&flagsConfig.Proto.Exclude), flagExclude, "Glob of .proto files not to compile, e.g., \"google/**\". Can be repeated.")
+	defaultSet.Var((*stringsFlag)(&flagsConfig.Proto.DescriptorSets), flagDescriptorSet, "Path to a binary (or JSON, if the file has a .json extension) FileDescriptorSet file. Can be repeated.")
+	defaultSet.StringVar(&flagsConfig.Profile, flagProfile, "", "Name of a database profile defined in the config file to use.")
+	defaultSet.StringVar(&flagsConfig.DB.Driver, flagDriver, "", "Database driver name. Possible values: postgres, sqlite, mysql, sqlserver.")
 	defaultSet.StringVar(&flagsConfig.DB.Host, flagHost, "", "Host name or IP address.")
 	defaultSet.IntVar(&flagsConfig.DB.Port, flagPort, 0, "Port number.")
-	defaultSet.StringVar(&flagsConfig.DB.Name, flagName, "", "Database name.")
+	defaultSet.StringVar(&flagsConfig.DB.Name, flagName, "", "Database name, or database file path for sqlite.")
 	defaultSet.StringVar(&flagsConfig.DB.UserName, flagUserName, "", "User name.")
 	defaultSet.StringVar(&flagsConfig.DB.Password, flagPassword, "", "Password.")
+	defaultSet.StringVar(&flagsConfig.Output.Format, flagFormat, "", fmt.Sprintf("Output format. Possible values: table, csv, tsv, json, ndjson. If not provided, %q is assumed.", defaultFormat))
+	defaultSet.IntVar(&flagsConfig.Output.Spacing, flagSpacing, 0, fmt.Sprintf("Number of spaces between table cells. If not provided, %d is assumed.", defaultSpacing))
+	defaultSet.StringVar(&flagsConfig.Script.File, flagScriptFile, "", "Path to a file to run statements from.")
+	defaultSet.StringVar(&flagsConfig.Script.OnError, flagOnError, "", fmt.Sprintf("What to do when a statement run from a file or a pipe fails. Possible values: %[1]s, %[2]s. If not provided, %[1]q is assumed.", OnErrorStop, OnErrorContinue))
 	noAutoMap := defaultSet.Bool(flagNoAutoMap, false, "Do not auto-decode values in columns whose names match message aliases.")
 	undeterministic := defaultSet.Bool(flagUndeterministic, false, "Do not use deterministic protobuf serialization.")
+	noHeader := defaultSet.Bool(flagNoHeader, false, "Do not print column names.")
+	defaultSet.BoolVar(&flagsConfig.Script.DryRun, flagDryRun, false, "Print statements as they would be sent to the database, along with their encoded params and decoded columns, instead of running them.")
+	defaultSet.BoolVar(&flagsConfig.Script.SingleTransaction, flagSingleTx, false, "Run all statements in a single transaction, rolled back on the first error.")
 	if p.mute {
 		defaultSet.SetOutput(io.Discard)
 	}
 	if err = defaultSet.Parse(p.args); err != nil {
 		return "", nil, nil, err // possible flag.ErrHelp // TODO: handle it in main
 	}
-	flagsConfig.DB.Query = defaultSet.Arg(0)
+	switch cmd := defaultSet.Arg(0); cmd {
+	case CommandEncode, CommandDecode:
+		if flagsConfig.Command, err = p.parseCommand(cmd, defaultSet.Args()[1:]); err != nil {
+			return "", nil, nil, err
+		}
+	default:
+		flagsConfig.DB.Query = cmd
+	}
 	flagsConfig.Messages.AutoMap = !*noAutoMap
 	flagsConfig.Proto.Deterministic = !*undeterministic
+	flagsConfig.Output.Header = !*noHeader
 
 	m := map[string]struct{}{}
 	defaultSet.Visit(func(f *flag.Flag) {
@@ -75,44 +108,158 @@ func (p *parser) parseCLArgs() (filePath string, flagsConfig *rawConfig, isSet f
 	return filePath, flagsConfig, isSet, nil
 }
 
+// parseCommand parses the args of an offline subcommand. Unlike the top-level flags, its flags can follow its args,
+// e.g., `decode e CgQI --base64`; args following `--` are never treated as flags, e.g., `encode foo -- -1`.
+func (p *parser) parseCommand(name string, args []string) (*Command, error) {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	hex := set.Bool(flagHex, false, "Hex-encoded bytes. The default.")
+	base64 := set.Bool(flagBase64, false, "Base64-encoded bytes.")
+	if p.mute {
+		set.SetOutput(io.Discard)
+	}
+	res := &Command{Name: name, Args: []string{}}
+	for len(args) > 0 {
+		if err := set.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := set.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			res.Args = append(res.Args, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		res.Args = append(res.Args, rest[0])
+		args = rest[1:]
+	}
+	switch {
+	case *hex && *base64:
+		return nil, fmt.Errorf("-%s and -%s cannot be used together", flagHex, flagBase64)
+	case *base64:
+		res.Encoding = EncodingBase64
+	default:
+		res.Encoding = EncodingHex
+	}
+	return res, nil
+}
+
 func (p *parser) parseFile(filePath string, isSet bool) (*rawConfig, error) {
 	res := newRawConfig()
 	if !isSet {
-		filePath = defaultConfigFileName
-	}
-	fileName := filepath.Base(filePath)
-	fsys := p.makeFS(filepath.Dir(filePath))
-	if _, err := fs.Stat(fsys, fileName); err != nil {
-		if !isSet {
+		var ok bool
+		if filePath, ok = p.defaultFile(); !ok {
 			// default config file does not exists
 			// it's OK
 			return res, nil
 		}
+	}
+	fileName := filepath.Base(filePath)
+	fsys := p.makeFS(filepath.Dir(filePath))
+	if _, err := fs.Stat(fsys, fileName); err != nil {
 		return nil, fmt.Errorf("could not open file %q: %w", filePath, err)
 	}
 	bytes, err := fs.ReadFile(fsys, fileName)
 	if err != nil {
 		return nil, fmt.Errorf("could not read file %q: %w", filePath, err)
 	}
+	if bytes, err = toJSON(filePath, bytes); err != nil {
+		return nil, fmt.Errorf("could not parse file %q: %w", filePath, err)
+	}
 	if err := res.from(bytes); err != nil {
 		return nil, err
 	}
+	res.Messages.setFile(filePath)
+	if err := p.parseIncludes(fsys, filePath, res); err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
+// defaultFile returns the first of the default config files found in the working directory, if any.
+func (p *parser) defaultFile() (string, bool) {
+	fsys := p.makeFS(".")
+	for _, name := range defaultConfigFileNames {
+		if _, err := fs.Stat(fsys, name); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// parseIncludes merges the messages defined in the files matching raw.Include into raw.Messages.
+// The patterns are those of fs.Glob, relative to the directory of the config file at filePath, which fsys is rooted at.
+// A pattern pointing outside of that directory, e.g., `../shared/*.json`, or matching no files is an error.
+func (p *parser) parseIncludes(fsys fs.FS, filePath string, raw *rawConfig) error {
+	dir := filepath.Dir(filePath)
+	for _, pattern := range raw.Include {
+		if clean := path.Clean(pattern); path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("include %q points outside of the directory of %q", pattern, filePath)
+		}
+		names, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return fmt.Errorf("invalid include %q: %w", pattern, err)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("include %q does not match any files", pattern)
+		}
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if path == filepath.Clean(filePath) {
+				continue // e.g., *.json
+			}
+			bytes, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return fmt.Errorf("could not read file %q: %w", path, err)
+			}
+			if bytes, err = toJSON(path, bytes); err != nil {
+				return fmt.Errorf("could not parse file %q: %w", path, err)
+			}
+			messages := &messagesConfig{}
+			if err := messages.from(bytes, path); err != nil {
+				return fmt.Errorf("could not parse file %q: %w", path, err)
+			}
+			if err := raw.Messages.mergeMessages(messages); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (p *parser) from(raw *rawConfig) (res *Config, err error) {
 	if raw == nil {
 		return nil, nil
 	}
 	res = &Config{}
 	res.Proto = raw.Proto
+	res.Profile = raw.Profile
 	res.DB = raw.DB
+	res.Output = raw.Output
+	res.Script = raw.Script
+	res.Command = raw.Command
 	if res.InMessages, err = p.in.parse(raw.Messages.In); err != nil {
 		return nil, err
 	}
-	if res.OutMessages, err = p.out.parse(raw.Messages.Out); err != nil {
+	if res.OutMessages, err = p.out.parse(raw.Messages.Out, raw.Messages.OutFormat); err != nil {
 		return nil, err
 	}
 	res.AutoMapOutMessages = raw.Messages.AutoMap
+	res.messages = raw.Messages
 	return res, nil
 }
+
+// stringsFlag is a flag.Value collecting the values of a repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
@@ -23,6 +23,7 @@ func newInMessageParser() *inMessageParser {
 
 func (p *inMessageParser) parse(m map[string]*inMessageConfig) (map[string]*InMessage, error) {
 	res := make(map[string]*InMessage, len(m))
+	files := make(map[string]string, len(m)) // keyed by alias
 	for aliasWithParams, imc := range m {
 		im, err := p.parseMessage(aliasWithParams, imc)
 		if err != nil {
@@ -31,10 +32,11 @@ func (p *inMessageParser) parse(m map[string]*inMessageConfig) (map[string]*InMe
 		// only duplicate aliases with different param definition (param number, param names, different whitespacing, etc.) can be detected,
 		// exactly the same aliases+params will not be treated as duplicates with the last one taking precedence.
 		// see outMessageParser.parse for more details
-		if _, ok := res[im.Alias]; ok {
-			return nil, fmt.Errorf("duplicate in message alias: %q", im.Alias)
+		if file, ok := files[im.Alias]; ok {
+			return nil, duplicateAliasError("in", im.Alias, file, imc.file)
 		}
 		res[im.Alias] = im
+		files[im.Alias] = imc.file
 	}
 	return res, nil
 }
@@ -17,14 +17,21 @@ type outMessageParser struct {
 func newOutMessageParser() *outMessageParser {
 	return &outMessageParser{
 		aliasrx: regexp.MustCompile(`^\w+$`),
-		tplrx:   regexp.MustCompile(`(?P<prefix>[^\\]|^)(?P<marker>\$)(?P<prop>(\w+\.)*\w+)`), // $ can be escaped with with \$ (\\$ in json)
+		tplrx:   regexp.MustCompile(`(?P<prefix>[^\\]|^)(?P<marker>\$)(?P<prop>` + tmpl.PropPattern + `)`), // $ can be escaped with with \$ (\\$ in json)
 	}
 }
 
-func (p *outMessageParser) parse(m map[string]*outMessageConfig) (map[string]*OutMessage, error) {
+// parse parses the out-messages in m, which are rendered in format when they have no template, OutMessageFormatJSON if format is empty.
+func (p *outMessageParser) parse(m map[string]*outMessageConfig, format string) (map[string]*OutMessage, error) {
+	if format == "" {
+		format = OutMessageFormatJSON
+	}
+	if err := p.validateFormat(format); err != nil {
+		return nil, err
+	}
 	res := make(map[string]*OutMessage, len(m))
 	for alias, omc := range m {
-		om, err := p.parseMessage(alias, omc)
+		om, err := p.parseMessage(alias, omc, format)
 		if err != nil {
 			return nil, err
 		}
@@ -36,7 +43,7 @@ func (p *outMessageParser) parse(m map[string]*outMessageConfig) (map[string]*Ou
 	return res, nil
 }
 
-func (p *outMessageParser) parseMessage(rawAlias string, omc *outMessageConfig) (*OutMessage, error) {
+func (p *outMessageParser) parseMessage(rawAlias string, omc *outMessageConfig, format string) (*OutMessage, error) {
 	alias, err := p.parseAlias(rawAlias)
 	if err != nil {
 		return nil, err
@@ -50,9 +57,18 @@ func (p *outMessageParser) parseMessage(rawAlias string, omc *outMessageConfig)
 		Name:     omc.Name,
 		Template: tpl,
 		Props:    props,
+		Format:   format,
 	}, nil
 }
 
+func (p *outMessageParser) validateFormat(format string) error {
+	switch format {
+	case OutMessageFormatJSON, OutMessageFormatText:
+		return nil
+	}
+	return fmt.Errorf("invalid out message format: %q", format)
+}
+
 func (p *outMessageParser) parseAlias(alias string) (string, error) {
 	res, ok := rx.FindMatch(p.aliasrx, alias)
 	if !ok {
@@ -63,10 +79,14 @@ func (p *outMessageParser) parseAlias(alias string) (string, error) {
 
 func (p *outMessageParser) parseTemplate(alias, tpl string) (res *template.Template, props map[string]struct{}, err error) {
 	props = map[string]struct{}{}
+	if tpl == "" {
+		// no template -- entire messages are rendered using the configured format
+		return nil, props, nil
+	}
 	s := rx.ReplaceAllGroupsFunc(p.tplrx, tpl, func(groups map[string]string) string {
 		prop := groups["prop"]
 		props[prop] = struct{}{}
-		return groups["prefix"] + "{{." + tmpl.PropToTemplateParam(prop) + "}}"
+		return groups["prefix"] + tmpl.PropToTemplateAction(prop)
 	})
 	s = strings.ReplaceAll(s, "\\$", "$") // unescape any `\$`s after rx-replace is done
 	res, err = template.New(alias).Option("missingkey=default").Parse(s)
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/m18/cpb/internal/testcheck"
@@ -49,9 +50,10 @@ func TestOutMessageParseTemplate(t *testing.T) {
 	tests := []struct {
 		tpl           string
 		expectedProps map[string]struct{}
+		expectedNil   bool
 		err           bool
 	}{
-		{tpl: "", expectedProps: map[string]struct{}{}},
+		{tpl: "", expectedProps: map[string]struct{}{}, expectedNil: true},
 		{tpl: " ", expectedProps: map[string]struct{}{}},
 		{
 			tpl:           `foo - $foo, bar: $bar`,
@@ -89,6 +91,18 @@ func TestOutMessageParseTemplate(t *testing.T) {
 			tpl:           `(foobar: \"$foo.bar\", \n foobarbaz = $foo.bar.baz)`,
 			expectedProps: map[string]struct{}{"foo.bar": {}, "foo.bar.baz": {}},
 		},
+		{
+			tpl:           `phones: $phones[*].number, first: $phones[0].number; env: $labels["env"] $labels["a.b \"c\""]`,
+			expectedProps: map[string]struct{}{"phones[*].number": {}, "phones[0].number": {}, `labels["env"]`: {}, `labels["a.b \"c\""]`: {}},
+		},
+		{
+			tpl:           `$phones[0] $ids[*]`,
+			expectedProps: map[string]struct{}{"phones[0]": {}, "ids[*]": {}},
+		},
+		{
+			tpl:           `$phones[x] $phones[]`, // not indexes
+			expectedProps: map[string]struct{}{"phones": {}},
+		},
 		{
 			tpl: `{$foo}`, // this "{" needs escaping
 			err: true,
@@ -104,8 +118,8 @@ func TestOutMessageParseTemplate(t *testing.T) {
 			if test.err {
 				return
 			}
-			if tpl == nil {
-				t.Fatalf("expected template to not be nil but it was")
+			if (tpl == nil) != test.expectedNil {
+				t.Fatalf("expected template to be nil: %t, but it was not", test.expectedNil)
 			}
 			if !eq.StringSets(props, test.expectedProps) {
 				t.Fatalf("expected %v but got %v", test.expectedProps, props)
@@ -180,7 +194,7 @@ func TestOutMessageParseMessage(t *testing.T) {
 		test := test
 		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
-			om, err := p.parseMessage(test.rawAlias, &test.omc)
+			om, err := p.parseMessage(test.rawAlias, &test.omc, OutMessageFormatJSON)
 			testcheck.FatalIfUnexpected(t, err, test.err)
 			if test.err {
 				return
@@ -194,6 +208,12 @@ func TestOutMessageParseMessage(t *testing.T) {
 			if om.Name != test.expectedName {
 				t.Fatalf("expected name to be %q but it was %q", test.expectedName, om.Name)
 			}
+			if (om.Template == nil) != (test.omc.Template == "") {
+				t.Fatalf("expected template to be nil only if it was not defined")
+			}
+			if om.Format != OutMessageFormatJSON {
+				t.Fatalf("expected format to be %q but it was %q", OutMessageFormatJSON, om.Format)
+			}
 		})
 	}
 }
@@ -236,20 +256,33 @@ func TestOutMessageParse(t *testing.T) {
 	tests := []struct {
 		desc                    string
 		omcs                    map[string]*outMessageConfig
+		format                  string
 		expectedAliasesAndNames map[string]protoreflect.FullName
+		expectedFormat          string
 		err                     bool
 	}{
 		{
-			desc: "valid config",
-			omcs: makeomcs(validConfig),
+			desc:   "valid config",
+			omcs:   makeomcs(validConfig),
+			format: OutMessageFormatJSON,
 			expectedAliasesAndNames: map[string]protoreflect.FullName{
 				"foo": "proto.Foo",
 				"bar": "proto.Bar",
 			},
 		},
 		{
-			desc: "duplicate alias, last one takes precedence",
-			omcs: makeomcs(duplicateAliasConfig),
+			desc:   "valid config, text format",
+			omcs:   makeomcs(validConfig),
+			format: OutMessageFormatText,
+			expectedAliasesAndNames: map[string]protoreflect.FullName{
+				"foo": "proto.Foo",
+				"bar": "proto.Bar",
+			},
+		},
+		{
+			desc:   "duplicate alias, last one takes precedence",
+			omcs:   makeomcs(duplicateAliasConfig),
+			format: OutMessageFormatJSON,
 			expectedAliasesAndNames: map[string]protoreflect.FullName{
 				"foo": "proto.Bar",
 			},
@@ -257,15 +290,31 @@ func TestOutMessageParse(t *testing.T) {
 		{
 			desc:                    "empty config",
 			omcs:                    nil,
+			format:                  OutMessageFormatJSON,
 			expectedAliasesAndNames: nil,
 		},
+		{
+			desc:   "invalid format",
+			omcs:   makeomcs(validConfig),
+			format: "foo",
+			err:    true,
+		},
+		{
+			desc: "no format, defaults to json",
+			omcs: makeomcs(validConfig),
+			expectedAliasesAndNames: map[string]protoreflect.FullName{
+				"foo": "proto.Foo",
+				"bar": "proto.Bar",
+			},
+			expectedFormat: OutMessageFormatJSON,
+		},
 	}
 	p := newOutMessageParser()
 	for _, test := range tests {
 		test := test
 		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
-			oms, err := p.parse(test.omcs)
+			oms, err := p.parse(test.omcs, test.format)
 			testcheck.FatalIfUnexpected(t, err, test.err)
 			if test.err {
 				return
@@ -282,6 +331,52 @@ func TestOutMessageParse(t *testing.T) {
 				if om.Name != expectedName {
 					t.Fatalf("expected name to be %q but it was %q", expectedName, om.Name)
 				}
+				if test.expectedFormat != "" && om.Format != test.expectedFormat {
+					t.Fatalf("expected format to be %q but it was %q", test.expectedFormat, om.Format)
+				}
+			}
+		})
+	}
+}
+
+func TestOutMessageParseTemplateExecute(t *testing.T) {
+	tests := []struct {
+		tpl      string
+		data     map[string]interface{}
+		expected string
+	}{
+		{
+			tpl:      `$foo.bar`,
+			data:     map[string]interface{}{"foo_bar": 1},
+			expected: "1",
+		},
+		{
+			tpl:      `$ids[*] ($ids[0])`,
+			data:     map[string]interface{}{"ids[*]": "1, 2", "ids[0]": 1},
+			expected: "1, 2 (1)",
+		},
+		{
+			tpl:      `$labels["env"]`,
+			data:     map[string]interface{}{`labels["env"]`: "prod"},
+			expected: "prod",
+		},
+		{
+			tpl:      `$labels["env"]`,
+			data:     map[string]interface{}{},
+			expected: "<no value>",
+		},
+	}
+	p := newOutMessageParser()
+	for _, test := range tests {
+		test := test
+		t.Run(test.tpl, func(t *testing.T) {
+			t.Parallel()
+			tpl, _, err := p.parseTemplate("foo", test.tpl)
+			testcheck.FatalIf(t, err)
+			var sb strings.Builder
+			testcheck.FatalIf(t, tpl.Execute(&sb, test.data))
+			if res := sb.String(); res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
 			}
 		})
 	}
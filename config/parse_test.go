@@ -3,11 +3,15 @@ package config
 import (
 	"fmt"
 	"io/fs"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/m18/cpb/internal/testcheck"
 	"github.com/m18/cpb/internal/testfs"
+	"github.com/m18/eq"
 )
 
 func TestParserParseCLArgs(t *testing.T) {
@@ -40,9 +44,12 @@ func TestParserParseCLArgs(t *testing.T) {
 				"-" + flagName, testExpectedName,
 				"-" + flagUserName, testExpectedUserName,
 				"-" + flagPassword, testExpectedPassword,
+				"-" + flagFormat, testExpectedFormat,
+				"-" + flagNoHeader,
+				"-" + flagSpacing, strconv.Itoa(testExpectedSpacing),
 			},
 			expectedFilePath: defaultConfigFileName,
-			check:            testRawConfigCheck,
+			check:            testRawConfigWithOutputCheck,
 		},
 		{
 			args:          []string{testQuery},
@@ -64,10 +71,95 @@ func TestParserParseCLArgs(t *testing.T) {
 			expectedFilePath: "",
 			expectedQuery:    testQuery,
 		},
+		{
+			args: []string{
+				"-" + flagDescriptorSet, "foo.pb",
+				"-" + flagDescriptorSet, "bar.json",
+			},
+			check: func(c *rawConfig) error {
+				expected := []string{"foo.pb", "bar.json"}
+				if !eq.StringSlices(c.Proto.DescriptorSets, expected) {
+					return fmt.Errorf("expected descriptor sets to be %v but they were %v", expected, c.Proto.DescriptorSets)
+				}
+				return nil
+			},
+		},
+		{
+			args: []string{
+				"-" + flagImportPath, "foo",
+				"-" + flagImportPath, "bar",
+			},
+			check: func(c *rawConfig) error {
+				expected := []string{"foo", "bar"}
+				if !eq.StringSlices(c.Proto.ImportPaths, expected) {
+					return fmt.Errorf("expected import paths to be %v but they were %v", expected, c.Proto.ImportPaths)
+				}
+				return nil
+			},
+		},
+		{
+			args: []string{
+				"--" + flagInclude, "foo/**",
+				"--" + flagInclude, "bar/*.proto",
+				"--" + flagExclude, "foo/baz/*",
+			},
+			check: func(c *rawConfig) error {
+				if expected := []string{"foo/**", "bar/*.proto"}; !eq.StringSlices(c.Proto.Include, expected) {
+					return fmt.Errorf("expected include to be %v but it was %v", expected, c.Proto.Include)
+				}
+				if expected := []string{"foo/baz/*"}; !eq.StringSlices(c.Proto.Exclude, expected) {
+					return fmt.Errorf("expected exclude to be %v but it was %v", expected, c.Proto.Exclude)
+				}
+				return nil
+			},
+		},
+		{
+			args: []string{
+				"-" + flagScriptFile, "foo.sql",
+				"--" + flagOnError, OnErrorContinue,
+				"--" + flagSingleTx,
+				"--" + flagDryRun,
+			},
+			check: func(c *rawConfig) error {
+				if c.Script.File != "foo.sql" {
+					return fmt.Errorf("expected script file to be %q but it was %q", "foo.sql", c.Script.File)
+				}
+				if c.Script.OnError != OnErrorContinue {
+					return fmt.Errorf("expected on-error policy to be %q but it was %q", OnErrorContinue, c.Script.OnError)
+				}
+				if !c.Script.SingleTransaction {
+					return fmt.Errorf("expected single transaction to be true but it was not")
+				}
+				if !c.Script.DryRun {
+					return fmt.Errorf("expected dry run to be true but it was not")
+				}
+				return nil
+			},
+		},
 		{
 			args: []string{"-unknown"},
 			err:  true,
 		},
+		{
+			args: []string{"-" + flagFormat, "csv", CommandEncode, "foo", "1", "-" + flagBase64, "--", "-2", "-" + flagHex},
+			check: func(c *rawConfig) error {
+				return testCommandCheck(c, &Command{Name: CommandEncode, Args: []string{"foo", "1", "-2", "-" + flagHex}, Encoding: EncodingBase64})
+			},
+		},
+		{
+			args: []string{CommandDecode, "foo", "0a04"},
+			check: func(c *rawConfig) error {
+				return testCommandCheck(c, &Command{Name: CommandDecode, Args: []string{"foo", "0a04"}, Encoding: EncodingHex})
+			},
+		},
+		{
+			args: []string{CommandDecode, "-" + flagHex, "foo", "--" + flagBase64},
+			err:  true,
+		},
+		{
+			args: []string{CommandDecode, "foo", "-unknown"},
+			err:  true,
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -97,6 +189,19 @@ func TestParserParseCLArgs(t *testing.T) {
 	}
 }
 
+func testCommandCheck(c *rawConfig, expected *Command) error {
+	if c.Command == nil {
+		return fmt.Errorf("expected command to be %v but it was nil", expected)
+	}
+	if c.Command.Name != expected.Name || c.Command.Encoding != expected.Encoding || !eq.StringSlices(c.Command.Args, expected.Args) {
+		return fmt.Errorf("expected command to be %v but it was %v", expected, c.Command)
+	}
+	if c.DB.Query != "" {
+		return fmt.Errorf("expected query to be empty but it was %q", c.DB.Query)
+	}
+	return nil
+}
+
 func TestParserParseFile(t *testing.T) {
 	testFS, testFileName := testfs.MakeTestConfigFS(testConfigJSON)
 	testMakeFS := func(string) fs.FS {
@@ -119,14 +224,14 @@ func TestParserParseFile(t *testing.T) {
 			makeFS:   testMakeFS,
 			filePath: testFileName,
 			isSet:    true,
-			check:    testRawConfigCheck,
+			check:    testRawConfigWithOutputCheck,
 		},
 		{
 			desc:     "valid input, optional default config file",
 			makeFS:   testMakeFSDefault,
 			filePath: "foo",
 			isSet:    false,
-			check:    testRawConfigCheck,
+			check:    testRawConfigWithOutputCheck,
 		},
 		{
 			desc:     "non-existent file",
@@ -212,6 +317,8 @@ func TestParserParse(t *testing.T) {
 				"-" + flagDriver, "baz",
 				"-" + flagNoAutoMap,
 				"-" + flagUndeterministic,
+				"-" + flagFormat, "tsv",
+				"-" + flagSpacing, "0",
 			},
 			check: func(c *Config) error {
 				if c.Proto.C != "foo" {
@@ -229,6 +336,15 @@ func TestParserParse(t *testing.T) {
 				if c.Proto.Deterministic {
 					return fmt.Errorf("expected deterministic to be false but it was not")
 				}
+				if c.Output.Format != "tsv" {
+					return fmt.Errorf("expected format to be %q but it was %q", "tsv", c.Output.Format)
+				}
+				if c.Output.Header {
+					return fmt.Errorf("expected header to be false but it was not")
+				}
+				if c.Output.Spacing != 0 {
+					return fmt.Errorf("expected spacing to be %d but it was %d", 0, c.Output.Spacing)
+				}
 				return nil
 			},
 		},
@@ -252,3 +368,237 @@ func TestParserParse(t *testing.T) {
 		})
 	}
 }
+
+func TestStringsFlag(t *testing.T) {
+	var f stringsFlag
+	if s := f.String(); s != "" {
+		t.Fatalf("expected %q but got %q", "", s)
+	}
+	testcheck.FatalIf(t, f.Set("foo"))
+	testcheck.FatalIf(t, f.Set("bar"))
+	if !eq.StringSlices(f, []string{"foo", "bar"}) {
+		t.Fatalf("expected %v but got %v", []string{"foo", "bar"}, f)
+	}
+	if s := f.String(); s != "foo,bar" {
+		t.Fatalf("expected %q but got %q", "foo,bar", s)
+	}
+}
+
+func TestParserParseProfile(t *testing.T) {
+	testFS, testFileName := testfs.MakeTestConfigFS(`{
+		"profile": "dev",
+		"db": {
+			"driver": "postgres",
+			"host": "localhost",
+			"port": 5432,
+			"name": "cpb",
+			"userName": "cpb",
+			"params": {"sslmode": "disable"}
+		},
+		"profiles": {
+			"dev": {"host": "dev"},
+			"prod": {"host": "prod", "password": "secret", "params": {"sslmode": "require"}}
+		}
+	}`)
+	tests := []struct {
+		args             []string
+		env              map[string]string
+		expectedProfile  string
+		expectedHost     string
+		expectedPassword string
+		expectedSSLMode  string
+		err              bool
+	}{
+		{
+			expectedProfile: "dev",
+			expectedHost:    "dev",
+			expectedSSLMode: "disable",
+		},
+		{
+			args:             []string{"-" + flagProfile, "prod"},
+			expectedProfile:  "prod",
+			expectedHost:     "prod",
+			expectedPassword: "secret",
+			expectedSSLMode:  "require",
+		},
+		{
+			args:             []string{"-" + flagProfile, "prod", "-" + flagHost, "flag"},
+			env:              map[string]string{"CPB_DB_HOST": "env", "CPB_DB_PASSWORD": "env"},
+			expectedProfile:  "prod",
+			expectedHost:     "flag",
+			expectedPassword: "env",
+			expectedSSLMode:  "require",
+		},
+		{
+			args:            []string{"-" + flagProfile, ""},
+			expectedHost:    "localhost",
+			expectedSSLMode: "disable",
+		},
+		{
+			args: []string{"-" + flagProfile, "unknown"},
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(fmt.Sprint(test.args), func(t *testing.T) {
+			t.Parallel()
+			p := newParser(append([]string{"-" + FlagFile, testFileName}, test.args...), func(string) fs.FS { return testFS }, true)
+			p.lookupEnv = testLookupEnv(test.env)
+			cfg, err := p.parse()
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if cfg.Profile != test.expectedProfile {
+				t.Fatalf("expected profile to be %q but it was %q", test.expectedProfile, cfg.Profile)
+			}
+			if cfg.DB.Host != test.expectedHost {
+				t.Fatalf("expected host to be %q but it was %q", test.expectedHost, cfg.DB.Host)
+			}
+			if cfg.DB.Password != test.expectedPassword {
+				t.Fatalf("expected password to be %q but it was %q", test.expectedPassword, cfg.DB.Password)
+			}
+			if sslMode := cfg.DB.Params["sslmode"]; sslMode != test.expectedSSLMode {
+				t.Fatalf("expected sslmode to be %q but it was %q", test.expectedSSLMode, sslMode)
+			}
+			res, err := cfg.JSON()
+			testcheck.FatalIf(t, err)
+			if strings.Contains(res, "secret") || strings.Contains(res, `"profiles"`) {
+				t.Fatalf("expected %s to not contain profiles but it did", res)
+			}
+		})
+	}
+}
+
+func TestParserParseFileIncludes(t *testing.T) {
+	makeFS := func(config string, files map[string]string) func(string) fs.FS {
+		fsys := fstest.MapFS{"config.json": &fstest.MapFile{Data: []byte(config)}}
+		for name, data := range files {
+			fsys[name] = &fstest.MapFile{Data: []byte(data)}
+		}
+		return func(string) fs.FS { return fsys }
+	}
+	messages := map[string]string{
+		"messages/billing.json":   `{"in": {"invoice(id)": {"name": "billing.Invoice", "template": {"id": "$id"}}}, "out": {"invoice": {"name": "billing.Invoice"}}}`,
+		"messages/users/foo.json": `{"in": {"user(id)": {"name": "users.User", "template": {"id": "$id"}}}}`,
+		"messages/users/bar.json": `{"out": {"user": {"name": "users.User"}}}`,
+	}
+	tests := []struct {
+		desc        string
+		makeFS      func(string) fs.FS
+		expectedIn  []string
+		expectedOut []string
+		errContains []string
+	}{
+		{
+			desc: "files and globs",
+			makeFS: makeFS(`{
+				"include": ["messages/billing.json", "messages/users/*.json"],
+				"messages": {"in": {"foo()": {"name": "foo.Foo"}}}
+			}`, messages),
+			expectedIn:  []string{"foo", "invoice", "user"},
+			expectedOut: []string{"invoice", "user"},
+		},
+		{
+			desc: "yaml",
+			makeFS: makeFS(`{"include": ["messages/*.yaml"]}`, map[string]string{
+				"messages/users.yaml": "in:\n  user(id):\n    name: users.User\n    template:\n      id: $id\nout:\n  user:\n    name: users.User\n",
+			}),
+			expectedIn:  []string{"user"},
+			expectedOut: []string{"user"},
+		},
+		{
+			desc:        "config file matched by a glob",
+			makeFS:      makeFS(`{"include": ["*.json", "messages/billing.json"]}`, messages),
+			expectedIn:  []string{"invoice"},
+			expectedOut: []string{"invoice"},
+		},
+		{
+			desc: "same in-message alias and params",
+			makeFS: makeFS(`{
+				"include": ["messages/billing.json"],
+				"messages": {"in": {"invoice(id)": {"name": "foo.Foo"}}}
+			}`, messages),
+			errContains: []string{`"invoice(id)"`, `"config.json"`, `"messages/billing.json"`},
+		},
+		{
+			desc: "same in-message alias, different params",
+			makeFS: makeFS(`{"include": ["messages/*.json", "more/*.json"]}`, map[string]string{
+				"messages/billing.json": messages["messages/billing.json"],
+				"more/billing.json":     `{"in": {"invoice(id, date)": {"name": "billing.Invoice", "template": {"id": "$id", "date": "$date"}}}}`,
+			}),
+			errContains: []string{`"invoice"`, `"messages/billing.json"`, `"more/billing.json"`},
+		},
+		{
+			desc: "same out-message alias",
+			makeFS: makeFS(`{"include": ["messages/billing.json", "more/billing.json"]}`, map[string]string{
+				"messages/billing.json": messages["messages/billing.json"],
+				"more/billing.json":     `{"out": {"invoice": {"name": "billing.Invoice"}}}`,
+			}),
+			errContains: []string{`"invoice"`, `"messages/billing.json"`, `"more/billing.json"`},
+		},
+		{
+			desc:        "no matches",
+			makeFS:      makeFS(`{"include": ["messages/none/*.json"]}`, messages),
+			errContains: []string{`"messages/none/*.json"`, "does not match"},
+		},
+		{
+			desc:        "outside of the config directory",
+			makeFS:      makeFS(`{"include": ["../shared/*.json"]}`, messages),
+			errContains: []string{`"../shared/*.json"`, "outside"},
+		},
+		{
+			desc:        "outside of the config directory after cleaning",
+			makeFS:      makeFS(`{"include": ["messages/../../billing.json"]}`, messages),
+			errContains: []string{`"messages/../../billing.json"`, "outside"},
+		},
+		{
+			desc:        "absolute path",
+			makeFS:      makeFS(`{"include": ["/etc/cpb/*.json"]}`, messages),
+			errContains: []string{`"/etc/cpb/*.json"`, "outside"},
+		},
+		{
+			desc:        "invalid pattern",
+			makeFS:      makeFS(`{"include": ["messages/["]}`, messages),
+			errContains: []string{`"messages/["`},
+		},
+		{
+			desc:        "invalid file",
+			makeFS:      makeFS(`{"include": ["messages/invalid.json"]}`, map[string]string{"messages/invalid.json": "{"}),
+			errContains: []string{`"messages/invalid.json"`},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := newParser([]string{"-" + FlagFile, "config.json"}, test.makeFS, true).parse()
+			testcheck.FatalIfUnexpected(t, err, len(test.errContains) > 0)
+			if err != nil {
+				for _, s := range test.errContains {
+					if !strings.Contains(err.Error(), s) {
+						t.Fatalf("expected error %q to contain %s but it did not", err, s)
+					}
+				}
+				return
+			}
+			in := []string{}
+			for alias := range cfg.InMessages {
+				in = append(in, alias)
+			}
+			out := []string{}
+			for alias := range cfg.OutMessages {
+				out = append(out, alias)
+			}
+			sort.Strings(in)
+			sort.Strings(out)
+			if !eq.StringSlices(in, test.expectedIn) {
+				t.Fatalf("expected in-messages to be %v but they were %v", test.expectedIn, in)
+			}
+			if !eq.StringSlices(out, test.expectedOut) {
+				t.Fatalf("expected out-messages to be %v but they were %v", test.expectedOut, out)
+			}
+		})
+	}
+}
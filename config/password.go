@@ -0,0 +1,54 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/m18/cpb/sys"
+)
+
+// RunPasswordCommand sets the password to the output of PasswordCommand unless the password is already set or not used, e.g., by sqlite.
+//
+// It is not part of validation so that the command is only run when the database is actually accessed, which main decides.
+func (c *DBConfig) RunPasswordCommand() error {
+	if c.Password != "" || c.PasswordCommand == "" || c.Driver == driverSQLite {
+		return nil
+	}
+	password, err := runPasswordCommand(c.PasswordCommand)
+	if err != nil {
+		return fmt.Errorf("could not get password: %w", err)
+	}
+	c.Password = password
+	return nil
+}
+
+// runPasswordCommand runs command with the system shell and returns its output without the trailing newline.
+// The command shares the standard error of cpb, and the standard input if it is a terminal, e.g., to ask for a passphrase.
+// Piped input, e.g., statements to run, is left for cpb to read.
+func runPasswordCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var out bytes.Buffer
+	if sys.IsTerminal() {
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("password command failed: %w", err)
+	}
+	res := strings.TrimRight(out.String(), "\r\n")
+	if res == "" {
+		return "", errors.New("password command returned an empty password")
+	}
+	return res, nil
+}
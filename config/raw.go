@@ -2,30 +2,43 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 type rawConfig struct {
-	Proto    *Proto          `json:"proto"`
-	DB       *DBConfig       `json:"db"`
-	Messages *messagesConfig `json:"messages"`
+	Proto    *Proto               `json:"proto"`
+	Profile  string               `json:"profile,omitempty"` // the name of the profile to override DB with
+	DB       *DBConfig            `json:"db"`
+	Profiles map[string]*DBConfig `json:"profiles,omitempty"` // keyed by name
+	Output   *Output              `json:"output"`
+	Script   *Script              `json:"script"`
+	Messages *messagesConfig      `json:"messages"`
+	Include  []string             `json:"include,omitempty"` // globs of files defining more messages, relative to the config file
+
+	Command *Command `json:"-"` // command line only
 }
 
 type messagesConfig struct {
-	In      map[string]*inMessageConfig  `json:"in"`
-	Out     map[string]*outMessageConfig `json:"out"`
-	AutoMap bool                         `json:"autoMap"`
+	In        map[string]*inMessageConfig  `json:"in"`
+	Out       map[string]*outMessageConfig `json:"out"`
+	OutFormat string                       `json:"outFormat"`
+	AutoMap   bool                         `json:"autoMap"`
 }
 
 type inMessageConfig struct {
 	Name     protoreflect.FullName `json:"name"`
 	Template interface{}           `json:"template"` // for JSON objects, map[string]interface{} behind the interface{} type
+
+	file string // where the message is defined, for errors
 }
 
 type outMessageConfig struct {
 	Name     protoreflect.FullName `json:"name"`
-	Template string                `json:"template"`
+	Template string                `json:"template"` // optional
+
+	file string // where the message is defined, for errors
 }
 
 func newRawConfig() *rawConfig {
@@ -34,6 +47,14 @@ func newRawConfig() *rawConfig {
 			Deterministic: true,
 		},
 		DB: &DBConfig{},
+		Output: &Output{
+			Format:  defaultFormat,
+			Header:  true,
+			Spacing: defaultSpacing,
+		},
+		Script: &Script{
+			OnError: OnErrorStop,
+		},
 		Messages: &messagesConfig{
 			AutoMap: true,
 		},
@@ -47,9 +68,90 @@ func (c *rawConfig) from(b []byte) error {
 	return nil
 }
 
+// from parses the in- and out-messages defined in file, ignoring the other options.
+func (c *messagesConfig) from(b []byte, file string) error {
+	if err := json.Unmarshal(b, c); err != nil {
+		return err
+	}
+	c.setFile(file)
+	return nil
+}
+
+func (c *messagesConfig) setFile(file string) {
+	for _, imc := range c.In {
+		imc.file = file
+	}
+	for _, omc := range c.Out {
+		omc.file = file
+	}
+}
+
+// mergeMessages adds the in- and out-messages of other to c item by item.
+// Aliases defined in both are reported, along with the files they are defined in.
+func (c *messagesConfig) mergeMessages(other *messagesConfig) error {
+	if c.In == nil {
+		c.In = make(map[string]*inMessageConfig, len(other.In))
+	}
+	for aliasWithParams, imc := range other.In {
+		if existing, ok := c.In[aliasWithParams]; ok {
+			return duplicateAliasError("in", aliasWithParams, existing.file, imc.file)
+		}
+		c.In[aliasWithParams] = imc
+	}
+	if c.Out == nil {
+		c.Out = make(map[string]*outMessageConfig, len(other.Out))
+	}
+	for alias, omc := range other.Out {
+		if existing, ok := c.Out[alias]; ok {
+			return duplicateAliasError("out", alias, existing.file, omc.file)
+		}
+		c.Out[alias] = omc
+	}
+	return nil
+}
+
+func duplicateAliasError(kind, alias, file, otherFile string) error {
+	if file == otherFile {
+		return fmt.Errorf("duplicate %s message alias: %q", kind, alias)
+	}
+	if otherFile < file {
+		file, otherFile = otherFile, file // stable errors regardless of map iteration order
+	}
+	return fmt.Errorf("duplicate %s message alias %q in %q and %q", kind, alias, file, otherFile)
+}
+
+// applyProfile overrides DB with the non-zero fields of the selected profile, if any. Params are merged item by item.
+func (c *rawConfig) applyProfile() error {
+	if c.Profile == "" {
+		return nil
+	}
+	p, ok := c.Profiles[c.Profile]
+	if !ok {
+		return fmt.Errorf("unknown profile: %q", c.Profile)
+	}
+	mergeString(&c.DB.Driver, p.Driver, p.Driver != "")
+	mergeString(&c.DB.Host, p.Host, p.Host != "")
+	mergeInt(&c.DB.Port, p.Port, p.Port != 0)
+	mergeString(&c.DB.Name, p.Name, p.Name != "")
+	mergeString(&c.DB.UserName, p.UserName, p.UserName != "")
+	mergeString(&c.DB.Password, p.Password, p.Password != "")
+	mergeString(&c.DB.PasswordCommand, p.PasswordCommand, p.PasswordCommand != "")
+	if len(p.Params) > 0 && c.DB.Params == nil {
+		c.DB.Params = make(map[string]string, len(p.Params))
+	}
+	for k, v := range p.Params {
+		c.DB.Params[k] = v
+	}
+	return nil
+}
+
 func (c *rawConfig) merge(override *rawConfig, isSet func(string) bool) {
 	mergeString(&c.Proto.C, override.Proto.C, isSet(flagProtoc))
 	mergeString(&c.Proto.Dir, override.Proto.Dir, isSet(flagProtoDir))
+	mergeStrings(&c.Proto.ImportPaths, override.Proto.ImportPaths, isSet(flagImportPath))
+	mergeStrings(&c.Proto.Include, override.Proto.Include, isSet(flagInclude))
+	mergeStrings(&c.Proto.Exclude, override.Proto.Exclude, isSet(flagExclude))
+	mergeStrings(&c.Proto.DescriptorSets, override.Proto.DescriptorSets, isSet(flagDescriptorSet))
 	mergeString(&c.DB.Driver, override.DB.Driver, isSet(flagDriver))
 	mergeString(&c.DB.Host, override.DB.Host, isSet(flagHost))
 	mergeInt(&c.DB.Port, override.DB.Port, isSet(flagPort))
@@ -58,24 +160,19 @@ func (c *rawConfig) merge(override *rawConfig, isSet func(string) bool) {
 	mergeString(&c.DB.Password, override.DB.Password, isSet(flagPassword))
 	mergeBool(&c.Messages.AutoMap, override.Messages.AutoMap, isSet(flagNoAutoMap))
 	mergeBool(&c.Proto.Deterministic, override.Proto.Deterministic, isSet(flagUndeterministic))
+	mergeString(&c.Output.Format, override.Output.Format, isSet(flagFormat))
+	mergeBool(&c.Output.Header, override.Output.Header, isSet(flagNoHeader))
+	mergeInt(&c.Output.Spacing, override.Output.Spacing, isSet(flagSpacing))
+	mergeString(&c.Script.File, override.Script.File, isSet(flagScriptFile))
+	mergeString(&c.Script.OnError, override.Script.OnError, isSet(flagOnError))
+	mergeBool(&c.Script.SingleTransaction, override.Script.SingleTransaction, isSet(flagSingleTx))
+	mergeBool(&c.Script.DryRun, override.Script.DryRun, isSet(flagDryRun))
 	if override.DB.Query != "" {
 		c.DB.Query = override.DB.Query
 	}
-
-	// // TODO: handle DB.Params & Messages item by item
-	// if len(c.DB.Params) == 0 {
-	// 	c.DB.Params = secondary.DB.Params
-	// }
-	// if len(c.Messages.In) == 0 {
-	// 	c.Messages.In = secondary.Messages.In
-	// }
-	// if len(c.Messages.Out) == 0 {
-	// 	c.Messages.Out = secondary.Messages.Out
-	// }
-
-	// // TODO: merge ENV vars
-	// // 		 custom field tags `env:"blah"`
-	// //		 os.LookupEnv("")
+	if override.Command != nil {
+		c.Command = override.Command
+	}
 }
 
 func mergeString(target *string, v string, isSet bool) {
@@ -84,6 +181,12 @@ func mergeString(target *string, v string, isSet bool) {
 	}
 }
 
+func mergeStrings(target *[]string, v []string, isSet bool) {
+	if isSet {
+		*target = v
+	}
+}
+
 func mergeInt(target *int, v int, isSet bool) {
 	if isSet {
 		*target = v
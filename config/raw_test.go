@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/eq"
 )
 
 func TestRawConfigFrom(t *testing.T) {
@@ -202,3 +204,212 @@ func TestRawConfigMerge(t *testing.T) {
 		})
 	}
 }
+
+func TestRawConfigMergeOutput(t *testing.T) {
+	tests := []struct {
+		desc     string
+		base     func() *rawConfig
+		override func() *rawConfig
+		isSet    func(string) bool
+	}{
+		{
+			desc: "base only",
+			base: func() *rawConfig {
+				res := newRawConfig()
+				res.Output.Format = testExpectedFormat
+				res.Output.Header = false
+				res.Output.Spacing = testExpectedSpacing
+				return res
+			},
+			override: func() *rawConfig { return newRawConfig() },
+			isSet:    func(string) bool { return false },
+		},
+		{
+			desc: "override only",
+			base: func() *rawConfig { return newRawConfig() },
+			override: func() *rawConfig {
+				res := newRawConfig()
+				res.Output.Format = testExpectedFormat
+				res.Output.Header = false
+				res.Output.Spacing = testExpectedSpacing
+				return res
+			},
+			isSet: func(string) bool { return true },
+		},
+		{
+			desc: "partial intersection",
+			base: func() *rawConfig {
+				res := newRawConfig()
+				res.Output.Format = "tsv"
+				res.Output.Header = false
+				return res
+			},
+			override: func() *rawConfig {
+				res := newRawConfig()
+				res.Output.Format = testExpectedFormat
+				res.Output.Spacing = testExpectedSpacing
+				return res
+			},
+			isSet: func(name string) bool {
+				switch name {
+				case flagFormat, flagSpacing:
+					return true
+				default:
+					return false
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			base := test.base()
+			base.merge(test.override(), test.isSet)
+			testcheck.FatalIf(t, testOutputCheck(base.Output))
+		})
+	}
+}
+
+func TestRawConfigMergeDescriptorSets(t *testing.T) {
+	tests := []struct {
+		desc     string
+		base     []string
+		override []string
+		isSet    bool
+		expected []string
+	}{
+		{
+			desc:     "not set",
+			base:     []string{"foo.pb"},
+			override: []string{"bar.pb"},
+			expected: []string{"foo.pb"},
+		},
+		{
+			desc:     "set",
+			base:     []string{"foo.pb"},
+			override: []string{"bar.pb", "baz.pb"},
+			isSet:    true,
+			expected: []string{"bar.pb", "baz.pb"},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			base := newRawConfig()
+			base.Proto.DescriptorSets = test.base
+			override := newRawConfig()
+			override.Proto.DescriptorSets = test.override
+			base.merge(override, func(name string) bool { return test.isSet && name == flagDescriptorSet })
+			if !eq.StringSlices(base.Proto.DescriptorSets, test.expected) {
+				t.Fatalf("expected %v but got %v", test.expected, base.Proto.DescriptorSets)
+			}
+		})
+	}
+}
+
+func TestRawConfigMergeScript(t *testing.T) {
+	tests := []struct {
+		desc            string
+		isSet           func(string) bool
+		expectedFile    string
+		expectedOnError string
+	}{
+		{
+			desc:            "not set",
+			isSet:           func(string) bool { return false },
+			expectedFile:    "foo.sql",
+			expectedOnError: OnErrorStop,
+		},
+		{
+			desc:            "set",
+			isSet:           func(string) bool { return true },
+			expectedFile:    "bar.sql",
+			expectedOnError: OnErrorContinue,
+		},
+		{
+			desc:            "on-error only",
+			isSet:           func(name string) bool { return name == flagOnError },
+			expectedFile:    "foo.sql",
+			expectedOnError: OnErrorContinue,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			base := newRawConfig()
+			base.Script.File = "foo.sql"
+			override := newRawConfig()
+			override.Script.File = "bar.sql"
+			override.Script.OnError = OnErrorContinue
+			override.Script.SingleTransaction = true
+			base.merge(override, test.isSet)
+			if base.Script.File != test.expectedFile {
+				t.Fatalf("expected script file to be %q but it was %q", test.expectedFile, base.Script.File)
+			}
+			if base.Script.OnError != test.expectedOnError {
+				t.Fatalf("expected on-error policy to be %q but it was %q", test.expectedOnError, base.Script.OnError)
+			}
+			if expected := test.isSet(flagSingleTx); base.Script.SingleTransaction != expected {
+				t.Fatalf("expected single transaction to be %v but it was %v", expected, base.Script.SingleTransaction)
+			}
+		})
+	}
+}
+
+func TestRawConfigApplyProfile(t *testing.T) {
+	tests := []struct {
+		desc     string
+		profile  string
+		expected *DBConfig
+		err      bool
+	}{
+		{
+			desc:     "no profile",
+			expected: &DBConfig{Driver: "postgres", Host: "foo", Port: 5432, Params: map[string]string{"foo": "bar", "bar": "baz"}},
+		},
+		{
+			desc:     "overrides",
+			profile:  "prod",
+			expected: &DBConfig{Driver: "postgres", Host: "prod", Port: 5433, Password: "pwd", Params: map[string]string{"foo": "qux", "bar": "baz", "qux": "foo"}},
+		},
+		{
+			desc:     "another driver",
+			profile:  "local",
+			expected: &DBConfig{Driver: driverSQLite, Host: "foo", Port: 5432, Name: "local.db", Params: map[string]string{"foo": "bar", "bar": "baz"}},
+		},
+		{
+			desc:    "unknown profile",
+			profile: "unknown",
+			err:     true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			c := newRawConfig()
+			c.DB = &DBConfig{Driver: "postgres", Host: "foo", Port: 5432, Params: map[string]string{"foo": "bar", "bar": "baz"}}
+			c.Profiles = map[string]*DBConfig{
+				"prod":  {Host: "prod", Port: 5433, Password: "pwd", Params: map[string]string{"foo": "qux", "qux": "foo"}},
+				"local": {Driver: driverSQLite, Name: "local.db"},
+			}
+			c.Profile = test.profile
+			err := c.applyProfile()
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			db, expected := *c.DB, *test.expected
+			if !eq.StringMaps(db.Params, expected.Params) {
+				t.Fatalf("expected params to be %v but they were %v", expected.Params, db.Params)
+			}
+			db.Params, expected.Params = nil, nil
+			if fmt.Sprintf("%+v", db) != fmt.Sprintf("%+v", expected) {
+				t.Fatalf("expected db config to be %+v but it was %+v", expected, db)
+			}
+		})
+	}
+}
@@ -11,6 +11,8 @@ const (
 	testExpectedName     = "db"
 	testExpectedUserName = "unm"
 	testExpectedPassword = "pwd"
+	testExpectedFormat   = "csv"
+	testExpectedSpacing  = 3
 
 	testQuery = "select * from foo;"
 )
@@ -18,7 +20,8 @@ const (
 var testConfigJSON = fmt.Sprintf(`{
 	"proto": {
 		"c": "%s",
-		"dir": "%s"
+		"dir": "%s",
+		"descriptorSet": ["foo.pb", "bar.json"]
 	},
 	"db": {
 		"driver": "%s",
@@ -31,6 +34,11 @@ var testConfigJSON = fmt.Sprintf(`{
 			"foo": "bar"
 		}
 	},
+	"output": {
+		"format": "%s",
+		"header": false,
+		"spacing": %d
+	},
 	"messages": {
 		"in": {
 			"foo()": {
@@ -43,7 +51,7 @@ var testConfigJSON = fmt.Sprintf(`{
 			}
 		}
 	}
-}`, testExpectedProtoc, testExpectedProtoDir, testExpectedDriver, testExpectedHost, testExpectedPort, testExpectedName, testExpectedUserName, testExpectedPassword)
+}`, testExpectedProtoc, testExpectedProtoDir, testExpectedDriver, testExpectedHost, testExpectedPort, testExpectedName, testExpectedUserName, testExpectedPassword, testExpectedFormat, testExpectedSpacing)
 
 func testRawConfigCheck(c *rawConfig) error {
 	if c.Proto.C != testExpectedProtoc {
@@ -73,6 +81,13 @@ func testRawConfigCheck(c *rawConfig) error {
 	return nil
 }
 
+func testRawConfigWithOutputCheck(c *rawConfig) error {
+	if err := testRawConfigCheck(c); err != nil {
+		return err
+	}
+	return testOutputCheck(c.Output)
+}
+
 func testConfigCheck(c *Config) error {
 	if c.Proto.C != testExpectedProtoc {
 		return fmt.Errorf("expected protoc to be %q but it was %q", testExpectedProtoc, c.Proto.C)
@@ -101,5 +116,18 @@ func testConfigCheck(c *Config) error {
 	if !c.Proto.Deterministic {
 		return fmt.Errorf("expected deterministic to be true but it was not")
 	}
+	return testOutputCheck(c.Output)
+}
+
+func testOutputCheck(o *Output) error {
+	if o.Format != testExpectedFormat {
+		return fmt.Errorf("expected format to be %q but it was %q", testExpectedFormat, o.Format)
+	}
+	if o.Header {
+		return fmt.Errorf("expected header to be false but it was not")
+	}
+	if o.Spacing != testExpectedSpacing {
+		return fmt.Errorf("expected spacing to be %d but it was %d", testExpectedSpacing, o.Spacing)
+	}
 	return nil
 }
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// catalogQueries select (table name, column name) pairs of the user's tables and views, in column order.
+var catalogQueries = map[string]string{
+	DriverPostgres: `select table_name, column_name from information_schema.columns
+		where table_schema not in ('pg_catalog', 'information_schema')
+		order by table_name, ordinal_position`,
+	DriverSQLite: `select m.name, c.name from sqlite_master m join pragma_table_info(m.name) c
+		where m.type in ('table', 'view') and m.name not like 'sqlite_%'
+		order by m.name, c.cid`,
+	DriverMySQL: `select table_name, column_name from information_schema.columns
+		where table_schema = database()
+		order by table_name, ordinal_position`,
+	DriverSQLServer: `select table_name, column_name from information_schema.columns
+		order by table_name, ordinal_position`,
+}
+
+// Catalog returns the column names of the tables and views in the database, keyed by table name.
+func (d *DB) Catalog(ctx context.Context) (map[string][]string, error) {
+	rws, err := d.query(ctx, catalogQueries[d.driver]) // driver has already been validated
+	if err != nil {
+		return nil, fmt.Errorf("could not read catalog: %w", err)
+	}
+	defer rws.Close()
+	res := map[string][]string{}
+	for rws.Next() {
+		var table, col string
+		if err := rws.Scan(&table, &col); err != nil {
+			return nil, fmt.Errorf("could not read catalog: %w", err)
+		}
+		res[table] = append(res[table], col)
+	}
+	if err := rws.Err(); err != nil {
+		return nil, fmt.Errorf("could not read catalog: %w", err)
+	}
+	return res, nil
+}
@@ -1,17 +1,27 @@
 package db
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/m18/cpb/config"
 )
 
 var connStrGens = map[string]func(*config.DBConfig) (string, error){
 	// https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-PARAMKEYWORDS
-	"postgres": func(c *config.DBConfig) (string, error) {
+	DriverPostgres: func(c *config.DBConfig) (string, error) {
 		if c.Port == 0 {
-			c.Port = 5432
+			c.Port = defaultPostgresPort
+		}
+		if c.Password == "" {
+			// an empty password in the URL would stop the driver from looking it up
+			c.Password, _ = pgpassPassword(c)
 		}
 		s := fmt.Sprintf("postgres://%s@%s:%d/%s",
 			url.UserPassword(c.UserName, c.Password),
@@ -30,4 +40,66 @@ var connStrGens = map[string]func(*config.DBConfig) (string, error){
 		u.RawQuery = q.Encode()
 		return u.String(), nil
 	},
+	// https://www.sqlite.org/uri.html, https://pkg.go.dev/modernc.org/sqlite#Driver.Open
+	DriverSQLite: func(c *config.DBConfig) (string, error) {
+		if c.Name == "" {
+			return "", errors.New("database file path is not specified")
+		}
+		keys := make([]string, 0, len(c.Params))
+		for k := range c.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // for a stable order of pragmas
+		q := url.Values{}
+		for _, k := range keys {
+			q.Add("_pragma", fmt.Sprintf("%s(%s)", k, c.Params[k]))
+		}
+		res := "file:" + sqlitePathEscaper.Replace(c.Name)
+		if len(q) > 0 {
+			res += "?" + q.Encode()
+		}
+		return res, nil
+	},
+	// https://github.com/go-sql-driver/mysql#dsn-data-source-name
+	DriverMySQL: func(c *config.DBConfig) (string, error) {
+		if c.Port == 0 {
+			c.Port = 3306
+		}
+		cfg := mysql.NewConfig()
+		cfg.User = c.UserName
+		cfg.Passwd = c.Password
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+		cfg.DBName = c.Name
+		if len(c.Params) > 0 {
+			cfg.Params = c.Params
+		}
+		res := cfg.FormatDSN()
+		// params like parseTime are fields of mysql.Config rather than entries of its Params -- round-trip to validate them
+		if _, err := mysql.ParseDSN(res); err != nil {
+			return "", err
+		}
+		return res, nil
+	},
+	// https://github.com/microsoft/go-mssqldb#connection-parameters-and-dsn
+	DriverSQLServer: func(c *config.DBConfig) (string, error) {
+		if c.Port == 0 {
+			c.Port = 1433
+		}
+		u := &url.URL{
+			Scheme: "sqlserver",
+			User:   url.UserPassword(c.UserName, c.Password),
+			Host:   net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		}
+		q := url.Values{}
+		for k, v := range c.Params {
+			q.Set(k, v)
+		}
+		q.Set("database", c.Name)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	},
 }
+
+// sqlitePathEscaper escapes the characters that have special meaning in SQLite URI file names.
+var sqlitePathEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
@@ -1,5 +1,8 @@
 package db
 
 const (
-	DriverPostgres = "postgres"
+	DriverPostgres  = "postgres"
+	DriverSQLite    = "sqlite"
+	DriverMySQL     = "mysql"
+	DriverSQLServer = "sqlserver"
 )
@@ -3,21 +3,50 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/m18/cpb/config"
 	"github.com/m18/cpb/protos"
 
+	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	_ "github.com/microsoft/go-mssqldb"
+	_ "modernc.org/sqlite"
 )
 
+var anyType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// binaryTypes are the database type names of the columns whose []byte values are binary data rather than text.
+var binaryTypes = map[string]struct{}{
+	"BLOB":             {}, // mysql, sqlite
+	"TINYBLOB":         {}, // mysql
+	"MEDIUMBLOB":       {}, // mysql
+	"LONGBLOB":         {}, // mysql
+	"BINARY":           {}, // mysql, sqlserver
+	"VARBINARY":        {}, // mysql, sqlserver
+	"BIT":              {}, // mysql
+	"GEOMETRY":         {}, // mysql
+	"BYTEA":            {}, // postgres
+	"IMAGE":            {}, // sqlserver
+	"UNIQUEIDENTIFIER": {}, // sqlserver, 16 raw bytes
+}
+
 type DB struct {
-	c *sql.DB
-	p *queryParser
+	c      *sql.DB
+	conn   *sql.Conn // see session
+	tx     *sql.Tx   // see InTransaction
+	p      *queryParser
+	driver string
 }
 
 func New(cfg *config.DBConfig, protos *protos.Protos, inMessages map[string]*config.InMessage, outMessages map[string]*config.OutMessage, autoMapOutMessages bool) (*DB, error) {
-	connStr, err := connStrGens[cfg.Driver](cfg)
+	connStrGen, ok := connStrGens[cfg.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported driver: %s", cfg.Driver)
+	}
+	connStr, err := connStrGen(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -27,8 +56,9 @@ func New(cfg *config.DBConfig, protos *protos.Protos, inMessages map[string]*con
 	}
 
 	return &DB{
-		c: c,
-		p: newQueryParser(cfg.Driver, protos, inMessages, outMessages, autoMapOutMessages),
+		c:      c,
+		p:      newQueryParser(cfg.Driver, protos, inMessages, outMessages, autoMapOutMessages),
+		driver: cfg.Driver,
 	}, nil
 }
 
@@ -44,38 +74,99 @@ func (d *DB) Ping(ctx context.Context) error {
 }
 
 func (d *DB) Close() error {
+	if d.conn != nil {
+		d.conn.Close()
+	}
 	return d.c.Close()
 }
 
-func (d *DB) Query(ctx context.Context, q string) (cols []string, rows [][]interface{}, err error) {
+// Result is the result of a statement: either rows or, if the statement returns none, its status, e.g., UPDATE 3.
+type Result struct {
+	Cols   []string
+	Rows   [][]interface{}
+	Status string
+}
+
+func (d *DB) Query(ctx context.Context, q string) (*Result, error) {
 	q, inMessageArgs, outMessageStringers, err := d.p.parse(q)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+
+	kw := keyword(q)
+	if isExec(kw, q) {
+		// database/sql only reports rows affected by statements run with Exec
+		res, err := d.exec(ctx, q, btoi(inMessageArgs)...)
+		if err != nil {
+			return nil, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			n = -1 // not supported by the driver
+		}
+		return &Result{Status: status(kw, n)}, nil
 	}
 
 	rws, err := d.query(ctx, q, btoi(inMessageArgs)...)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	defer rws.Close() // the connection is not released until then, see session
 
-	cols, err = rws.Columns()
+	cols, err := rws.Columns()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if len(cols) == 0 {
+		// e.g., DDL
+		return &Result{Status: status(kw, -1)}, nil
 	}
 	colTypes, err := rws.ColumnTypes()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	colNames, colValTpls := getColData(colTypes)
-	rows, err = createRows(rws, colNames, colValTpls, outMessageStringers)
-	return cols, rows, err
+	rows, err := createRows(rws, colNames, colValTpls, textCols(colTypes), outMessageStringers)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Cols: cols, Rows: rows}, nil
 }
 
 func (d *DB) query(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	s, err := d.session(ctx)
+	if err != nil {
+		return nil, err
+	}
 	resc := make(chan *sql.Rows, 1)
 	errc := make(chan error, 1)
 	go func() {
-		res, err := d.c.QueryContext(ctx, q, args...)
+		res, err := s.QueryContext(ctx, q, args...)
+		if err != nil {
+			errc <- err
+		} else {
+			resc <- res
+		}
+	}()
+	select {
+	case err := <-errc:
+		return nil, err
+	case res := <-resc:
+		return res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (d *DB) exec(ctx context.Context, q string, args ...interface{}) (sql.Result, error) {
+	s, err := d.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resc := make(chan sql.Result, 1)
+	errc := make(chan error, 1)
+	go func() {
+		res, err := s.ExecContext(ctx, q, args...)
 		if err != nil {
 			errc <- err
 		} else {
@@ -106,12 +197,31 @@ func getColData(ct []*sql.ColumnType) ([]string, []interface{}) {
 		// instead, this approach uses slice of value-interfaces which we can turn into a temp slice of pointer-interfaces,
 		// pass it to rows.Scan(slice...), and then simply see the results in the original slice with no need for a type switch
 		// and specialized per type logic
-		colValTpls = append(colValTpls, reflect.New(c.ScanType()).Elem().Interface())
+		scanType := c.ScanType()
+		if scanType == nil {
+			// some drivers, e.g., sqlite, may not know the type of a column, e.g., an expression, upfront
+			scanType = anyType
+		}
+		colValTpls = append(colValTpls, reflect.New(scanType).Elem().Interface())
 	}
 	return colNames, colValTpls
 }
 
-func createRows(rows *sql.Rows, colNames []string, colValTpls []interface{}, outMessageStringers map[string]func([]byte) (string, error)) ([][]interface{}, error) {
+// textCols reports, for each column, whether its values are text even if the driver returns them as []byte,
+// e.g., mysql returns most values, including numbers, as []byte; lib/pq does the same for, e.g., numeric and json.
+//
+// Columns of unknown types, e.g., sqlite expressions, are not considered text since their values may be binary.
+func textCols(ct []*sql.ColumnType) []bool {
+	res := make([]bool, 0, len(ct))
+	for _, c := range ct {
+		name := strings.ToUpper(c.DatabaseTypeName())
+		_, binary := binaryTypes[name]
+		res = append(res, name != "" && !binary)
+	}
+	return res
+}
+
+func createRows(rows *sql.Rows, colNames []string, colValTpls []interface{}, textCols []bool, outMessageStringers map[string]func([]byte) (fmt.Stringer, error)) ([][]interface{}, error) {
 	colValTplPtrs := make([]interface{}, 0, len(colValTpls))
 	// a range loop won't work here because `for _, x := range colValTpls` would _copy_ the value into `x`
 	// and `&x` would not be pointing to the original value
@@ -128,6 +238,9 @@ func createRows(rows *sql.Rows, colNames []string, colValTpls []interface{}, out
 			if err != nil {
 				return nil, err
 			}
+			if b, ok := v.([]byte); ok && textCols[i] {
+				v = string(b)
+			}
 			resi = append(resi, v)
 		}
 		res = append(res, resi)
@@ -150,7 +263,7 @@ func btoi(params [][]byte) []interface{} {
 	return res
 }
 
-func getValue(dbVal interface{}, outMessageStringer func([]byte) (string, error)) (interface{}, error) {
+func getValue(dbVal interface{}, outMessageStringer func([]byte) (fmt.Stringer, error)) (interface{}, error) {
 	if dbVal == nil || outMessageStringer == nil {
 		return dbVal, nil
 	}
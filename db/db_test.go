@@ -1,9 +1,20 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
 	"testing"
 
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/cpb/internal/testconfig"
+	"github.com/m18/cpb/internal/testproto"
+	"github.com/m18/cpb/protos"
 	"github.com/m18/eq"
 )
 
@@ -48,9 +59,15 @@ func TestBtoi(t *testing.T) {
 	}
 }
 
+type testStringer string
+
+func (s testStringer) String() string {
+	return string(s)
+}
+
 func TestGetValue(t *testing.T) {
-	const stringerRes = "ok"
-	stringer := func([]byte) (string, error) {
+	const stringerRes testStringer = "ok"
+	stringer := func([]byte) (fmt.Stringer, error) {
 		return stringerRes, nil
 	}
 	tests := []struct {
@@ -98,7 +115,7 @@ func TestGetValue(t *testing.T) {
 		test := test
 		t.Run(fmt.Sprintf("dbVal: %v. stringer: %t", test.dbVal, test.useStringer), func(t *testing.T) {
 			t.Parallel()
-			var s func([]byte) (string, error)
+			var s func([]byte) (fmt.Stringer, error)
 			if test.useStringer {
 				s = stringer
 			}
@@ -113,3 +130,217 @@ func TestGetValue(t *testing.T) {
 		})
 	}
 }
+
+const testTextDriverName = "cpbtest-text"
+
+func init() {
+	sql.Register(testTextDriverName, testTextDriver{})
+}
+
+// testTextDriver mimics the text protocol of mysql: every value is returned as []byte, whatever the column type.
+type testTextDriver struct{}
+
+func (testTextDriver) Open(string) (driver.Conn, error) { return testTextConn{}, nil }
+
+type testTextConn struct{}
+
+func (testTextConn) Prepare(string) (driver.Stmt, error) { return testTextStmt{}, nil }
+func (testTextConn) Close() error                        { return nil }
+func (testTextConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type testTextStmt struct{}
+
+func (testTextStmt) Close() error  { return nil }
+func (testTextStmt) NumInput() int { return 0 }
+func (testTextStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (testTextStmt) Query([]driver.Value) (driver.Rows, error) { return &testTextRows{}, nil }
+
+var (
+	testTextCols   = []string{"id", "name", "price", "doc", "data", "expr"}
+	testTextTypes  = []string{"INT", "VARCHAR", "DECIMAL", "JSON", "BLOB", ""}
+	testTextValues = [][]driver.Value{
+		{[]byte("1"), []byte("foo"), []byte("1.50"), []byte(`{"a":1}`), []byte{1, 2}, []byte{3}},
+		{nil, nil, nil, nil, nil, nil},
+	}
+)
+
+type testTextRows struct {
+	i int
+}
+
+func (r *testTextRows) Columns() []string                       { return testTextCols }
+func (r *testTextRows) Close() error                            { return nil }
+func (r *testTextRows) ColumnTypeDatabaseTypeName(i int) string { return testTextTypes[i] }
+func (r *testTextRows) Next(dest []driver.Value) error {
+	if r.i == len(testTextValues) {
+		return io.EOF
+	}
+	copy(dest, testTextValues[r.i])
+	r.i++
+	return nil
+}
+
+func TestCreateRowsTextCols(t *testing.T) {
+	c, err := sql.Open(testTextDriverName, "")
+	testcheck.FatalIf(t, err)
+	defer c.Close()
+	rws, err := c.Query("select")
+	testcheck.FatalIf(t, err)
+	defer rws.Close()
+	colTypes, err := rws.ColumnTypes()
+	testcheck.FatalIf(t, err)
+	colNames, colValTpls := getColData(colTypes)
+	rows, err := createRows(rws, colNames, colValTpls, textCols(colTypes), nil)
+	testcheck.FatalIf(t, err)
+	expected := [][]interface{}{
+		{"1", "foo", "1.50", `{"a":1}`, []byte{1, 2}, []byte{3}},
+		{nil, nil, nil, nil, nil, nil},
+	}
+	if fmt.Sprintf("%#v", rows) != fmt.Sprintf("%#v", expected) {
+		t.Fatalf("expected %#v but got %#v", expected, rows)
+	}
+}
+
+func TestDBQuerySQLite(t *testing.T) {
+	// messages are compared as bytes in the where clause, which requires deterministic serialization
+	p, err := protos.New(
+		&config.Proto{C: testproto.Protoc, Dir: testproto.DirLite, Deterministic: true},
+		testproto.MakeFS,
+		testproto.MakeFileReg(),
+		testproto.Mute,
+	)
+	testcheck.FatalIf(t, err)
+	cfg, err := testconfig.MakeTestConfigLite(DriverSQLite)
+	testcheck.FatalIf(t, err)
+	cfg.DB.Name = filepath.Join(t.TempDir(), "test.db")
+	cfg.DB.Params = map[string]string{"journal_mode": "WAL"}
+	d, err := New(cfg.DB, p, cfg.InMessages, cfg.OutMessages, false)
+	testcheck.FatalIf(t, err)
+	defer d.Close()
+	ctx := context.Background()
+	testcheck.FatalIf(t, d.Ping(ctx))
+	for _, q := range []string{
+		"create table test (id integer, foo blob);",
+		"insert into test values (1, $foo(1, 'one', true)), (2, $foo(2, 'two', false));",
+	} {
+		_, err := d.Query(ctx, q)
+		testcheck.FatalIf(t, err)
+	}
+
+	res, err := d.Query(ctx, "select id, $foo:foo from test where foo = $foo(2, 'two', false);")
+	testcheck.FatalIf(t, err)
+	if expected := []string{"id", "foo"}; !eq.StringSlices(res.Cols, expected) {
+		t.Fatalf("expected columns to be %v but they were %v", expected, res.Cols)
+	}
+	if len(res.Rows) != 1 {
+		t.Fatalf("expected 1 row but got %d", len(res.Rows))
+	}
+	if id := fmt.Sprint(res.Rows[0][0]); id != "2" {
+		t.Fatalf("expected id to be %q but it was %q", "2", id)
+	}
+	expected := `{"id":2,"text":"two"}`
+	if foo := fmt.Sprint(res.Rows[0][1]); foo != expected {
+		t.Fatalf("expected foo to be %q but it was %q", expected, foo)
+	}
+
+	// RETURNING rows are decoded too
+	res, err = d.Query(ctx, "update test set id = 3 where id = 2 returning $foo:foo;")
+	testcheck.FatalIf(t, err)
+	if len(res.Rows) != 1 {
+		t.Fatalf("expected 1 row but got %d", len(res.Rows))
+	}
+	if foo := fmt.Sprint(res.Rows[0][0]); foo != expected {
+		t.Fatalf("expected foo to be %q but it was %q", expected, foo)
+	}
+}
+
+func TestDBQueryStatusSQLite(t *testing.T) {
+	cfg, err := testconfig.MakeTestConfigLite(DriverSQLite)
+	testcheck.FatalIf(t, err)
+	cfg.DB.Name = filepath.Join(t.TempDir(), "test.db")
+	d, err := New(cfg.DB, nil, nil, nil, false)
+	testcheck.FatalIf(t, err)
+	defer d.Close()
+	tests := []struct {
+		q              string
+		expectedStatus string
+		expectedRows   int
+	}{
+		{
+			q:              "create table test (id integer, name text);",
+			expectedStatus: "CREATE",
+		},
+		{
+			q:              "insert into test values (1, 'one'), (2, 'two'), (3, 'returning');",
+			expectedStatus: "INSERT 3",
+		},
+		{
+			q:              "-- comment\n update test set name = 'foo' where id > 1;",
+			expectedStatus: "UPDATE 2",
+		},
+		{
+			q:            "insert into test values (4, 'four') returning id, name;",
+			expectedRows: 1,
+		},
+		{
+			q:            "select * from test;",
+			expectedRows: 4,
+		},
+		{
+			q:            "select * from test where id < 0;",
+			expectedRows: 0,
+		},
+		{
+			q:              "delete from test;",
+			expectedStatus: "DELETE 4",
+		},
+	}
+	ctx := context.Background()
+	for _, test := range tests { // in order: statements depend on one another
+		res, err := d.Query(ctx, test.q)
+		testcheck.FatalIf(t, err)
+		if res.Status != test.expectedStatus {
+			t.Fatalf("%s: expected status to be %q but it was %q", test.q, test.expectedStatus, res.Status)
+		}
+		if len(res.Rows) != test.expectedRows {
+			t.Fatalf("%s: expected %d rows but got %d", test.q, test.expectedRows, len(res.Rows))
+		}
+		if test.expectedStatus == "" && len(res.Cols) == 0 {
+			t.Fatalf("%s: expected columns but got none", test.q)
+		}
+	}
+}
+
+func TestDBCatalogSQLite(t *testing.T) {
+	cfg, err := testconfig.MakeTestConfigLite(DriverSQLite)
+	testcheck.FatalIf(t, err)
+	cfg.DB.Name = filepath.Join(t.TempDir(), "test.db")
+	d, err := New(cfg.DB, nil, nil, nil, false)
+	testcheck.FatalIf(t, err)
+	defer d.Close()
+	ctx := context.Background()
+	for _, q := range []string{
+		"create table foo (id integer, bar blob, baz text);",
+		"create view qux as select id, baz from foo;",
+	} {
+		_, err := d.Query(ctx, q)
+		testcheck.FatalIf(t, err)
+	}
+
+	res, err := d.Catalog(ctx)
+	testcheck.FatalIf(t, err)
+	expected := map[string][]string{
+		"foo": {"id", "bar", "baz"},
+		"qux": {"id", "baz"},
+	}
+	if len(res) != len(expected) {
+		t.Fatalf("expected %v but got %v", expected, res)
+	}
+	for table, cols := range expected {
+		if !eq.StringSlices(res[table], cols) {
+			t.Fatalf("expected %v but got %v", expected, res)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/rx"
+)
+
+// Explanation describes what running a statement would involve, without running it.
+type Explanation struct {
+	Query       string                        // as sent to the database
+	Params      []*Param                      // in-message args bound to the query, in order
+	OutMessages map[string]*config.OutMessage // out-messages keyed by the names of the columns they decode
+}
+
+// Param is an in-message arg bound to a query.
+type Param struct {
+	Placeholder string // e.g., $1
+	InMessage   *config.InMessage
+	Bytes       []byte
+	JSON        string // protojson of the message encoded in Bytes
+}
+
+// Explain returns how q would be rewritten, which params would be bound to it, and which columns would be decoded.
+// It does not access the database.
+func (d *DB) Explain(q string) (*Explanation, error) {
+	return d.p.explain(q)
+}
+
+func (p *queryParser) explain(q string) (*Explanation, error) {
+	// aliases are matched the same way parseInMessageArgs does, and so are in the order of its args
+	groups, _ := rx.FindAllGroups(p.inqueryrx, q)
+	q, inMessageArgs, err := p.parseInMessageArgs(q)
+	if err != nil {
+		return nil, err
+	}
+	replace := p.inParamReplacer()
+	params := make([]*Param, 0, len(inMessageArgs))
+	for i, b := range inMessageArgs {
+		inMessage := p.inMessages[groups[i]["alias"]]
+		json, err := p.protos.ProtoJSON(inMessage.Name, b)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, &Param{
+			Placeholder: replace(""),
+			InMessage:   inMessage,
+			Bytes:       b,
+			JSON:        json,
+		})
+	}
+
+	q, outMessages, err := p.parseOutMessageCols(q)
+	if err != nil {
+		return nil, err
+	}
+	if p.autoMapOutMessages {
+		for alias, outMessage := range p.outMessages {
+			if _, ok := outMessages[alias]; !ok {
+				outMessages[alias] = outMessage
+			}
+		}
+	}
+
+	return &Explanation{
+		Query:       q,
+		Params:      params,
+		OutMessages: outMessages,
+	}, nil
+}
+
+// String renders e for humans, e.g.,
+//
+//	query: select name from people where person_id = $1
+//	params:
+//	  $1: sid (example.ID)
+//	    hex: 0a050a03666f6f
+//	    base64: CgUKA2Zvbw==
+//	    json: {"shardId":{"shard":"foo"}}
+//	decoded columns:
+//	  person_id: person_id (example.ID)
+func (e *Explanation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "query: %s\n", e.Query)
+	if len(e.Params) > 0 {
+		sb.WriteString("params:\n")
+		for _, p := range e.Params {
+			fmt.Fprintf(&sb, "  %s: %s (%s)\n", p.Placeholder, p.InMessage.Alias, p.InMessage.Name)
+			fmt.Fprintf(&sb, "    hex: %s\n", hex.EncodeToString(p.Bytes))
+			fmt.Fprintf(&sb, "    base64: %s\n", base64.StdEncoding.EncodeToString(p.Bytes))
+			fmt.Fprintf(&sb, "    json: %s\n", p.JSON)
+		}
+	}
+	if len(e.OutMessages) > 0 {
+		sb.WriteString("decoded columns:\n")
+		cols := make([]string, 0, len(e.OutMessages))
+		for col := range e.OutMessages {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+		for _, col := range cols {
+			om := e.OutMessages[col]
+			fmt.Fprintf(&sb, "  %s: %s (%s)\n", col, om.Alias, om.Name)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/cpb/internal/testconfig"
+	"github.com/m18/cpb/internal/testproto"
+	"github.com/m18/cpb/protos"
+	"github.com/m18/eq"
+)
+
+func TestQueryParserExplain(t *testing.T) {
+	p, err := protos.New(
+		&config.Proto{C: config.ProtocBuiltin, Dir: testproto.DirLite},
+		testproto.MakeFS,
+		testproto.MakeFileReg(),
+		testproto.Mute,
+	)
+	testcheck.FatalIf(t, err)
+	tests := []struct {
+		desc               string
+		driver             string
+		autoMapOutMessages bool
+		query              string
+		expectedQuery      string
+		expectedParams     []string // placeholder and JSON
+		expectedCols       []string // column and alias
+		err                bool
+	}{
+		{
+			desc:          "plain query",
+			driver:        DriverPostgres,
+			query:         "select * from test",
+			expectedQuery: "select * from test",
+		},
+		{
+			desc:          "in-messages and out-messages",
+			driver:        DriverPostgres,
+			query:         "select $foo:foo_col from test where foo = $foo(1, 'a', true) and bar = $bar(2, 'b')",
+			expectedQuery: "select foo_col from test where foo = $1 and bar = $2",
+			expectedParams: []string{
+				`$1 {"id":1,"text":"a","isOn":true}`,
+				`$2 {"id":2,"nested":{"name":"b"}}`,
+			},
+			expectedCols: []string{"foo_col foo"},
+		},
+		{
+			desc:           "mysql placeholders",
+			driver:         DriverMySQL,
+			query:          "select * from test where foo = $empty() and bar = $bar(1, 'b')",
+			expectedQuery:  "select * from test where foo = ? and bar = ?",
+			expectedParams: []string{`? {}`, `? {"id":1,"nested":{"name":"b"}}`},
+		},
+		{
+			desc:               "auto-map",
+			driver:             DriverPostgres,
+			autoMapOutMessages: true,
+			query:              "select $bar:foo from test",
+			expectedQuery:      "select foo from test",
+			expectedCols:       []string{"bar bar", "foo bar", "qux qux"},
+		},
+		{
+			desc:   "unknown alias",
+			driver: DriverPostgres,
+			query:  "select $unknown:foo_col from test",
+			err:    true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := testconfig.MakeTestConfigLite(test.driver)
+			testcheck.FatalIf(t, err)
+			qp := newQueryParser(cfg.DB.Driver, p, cfg.InMessages, cfg.OutMessages, test.autoMapOutMessages)
+			res, err := qp.explain(test.query)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if res.Query != test.expectedQuery {
+				t.Fatalf("expected query to be %q but it was %q", test.expectedQuery, res.Query)
+			}
+			params := []string{}
+			for _, param := range res.Params {
+				params = append(params, param.Placeholder+" "+param.JSON)
+			}
+			if !eq.StringSlices(params, test.expectedParams) {
+				t.Fatalf("expected params to be %v but they were %v", test.expectedParams, params)
+			}
+			cols := []string{}
+			for col, outMessage := range res.OutMessages {
+				cols = append(cols, col+" "+outMessage.Alias)
+			}
+			sort.Strings(cols)
+			if !eq.StringSlices(cols, test.expectedCols) {
+				t.Fatalf("expected decoded columns to be %v but they were %v", test.expectedCols, cols)
+			}
+		})
+	}
+}
+
+func TestExplanationString(t *testing.T) {
+	e := &Explanation{
+		Query: "select foo_col from test where foo = $1",
+		Params: []*Param{{
+			Placeholder: "$1",
+			InMessage:   &config.InMessage{Alias: "foo", Name: "testproto.lite.Foo"},
+			Bytes:       []byte{0x08, 0x01},
+			JSON:        `{"id":1}`,
+		}},
+		OutMessages: map[string]*config.OutMessage{
+			"foo_col": {Alias: "foo", Name: "testproto.lite.Foo"},
+			"bar_col": {Alias: "bar", Name: "testproto.lite.nested.Bar"},
+		},
+	}
+	expected := `query: select foo_col from test where foo = $1
+params:
+  $1: foo (testproto.lite.Foo)
+    hex: 0801
+    base64: CAE=
+    json: {"id":1}
+decoded columns:
+  bar_col: bar (testproto.lite.nested.Bar)
+  foo_col: foo (testproto.lite.Foo)
+`
+	if res := e.String(); res != expected {
+		t.Fatalf("expected\n%s\nbut got\n%s", expected, res)
+	}
+}
@@ -11,13 +11,27 @@ import (
 )
 
 var inParamReplacers = map[string]func() func(string) string{
-	DriverPostgres: func() func(string) string {
+	DriverPostgres:  numberedReplacer("$"),
+	DriverSQLite:    questionMarkReplacer,
+	DriverMySQL:     questionMarkReplacer,
+	DriverSQLServer: numberedReplacer("@p"),
+}
+
+// numberedReplacer returns a replacer of in-message args with numbered placeholders, e.g., $1, $2 for postgres.
+func numberedReplacer(prefix string) func() func(string) string {
+	return func() func(string) string {
 		counter := 0
 		return func(string) string {
 			counter = counter + 1
-			return fmt.Sprintf("$%d", counter)
+			return fmt.Sprintf("%s%d", prefix, counter)
 		}
-	},
+	}
+}
+
+func questionMarkReplacer() func(string) string {
+	return func(string) string {
+		return "?"
+	}
 }
 
 type queryParser struct {
@@ -31,6 +45,13 @@ type queryParser struct {
 }
 
 func newQueryParser(driver string, p *protos.Protos, inMessages map[string]*config.InMessage, outMessages map[string]*config.OutMessage, autoMapOutMessages bool) *queryParser {
+	inscalar := `'(\\'|[^'])*'|\d+(.\d+)?|true|false`
+	// arrays, e.g., [1, 2], go first so that their items are not matched as separate args
+	inarg := `\[\s*((` + inscalar + `)\s*(,\s*(` + inscalar + `)\s*)*)?\]|` + inscalar
+
+	// col names, either plain or quoted
+	ident := `\w+|"(\w+\s*)+"|\[(\w+\s*)+\]|` + "`(\\w+\\s*)+`"
+
 	inargnormrx := regexp.MustCompile(`'((\\'|[^'])*)'`) // checks for the presense of \' anywhere between a pair of single quotes
 	normalizer := func(args []string) []string {         // performs transformations like 'A string' -> "A string", 'O\'Reilly' -> "O'Reilly"
 		for i, arg := range args {
@@ -48,17 +69,17 @@ func newQueryParser(driver string, p *protos.Protos, inMessages map[string]*conf
 		inParamReplacer:    inParamReplacers[driver], // driver has already been validated
 		outMessages:        outMessages,
 		autoMapOutMessages: autoMapOutMessages,
-		inqueryrx:          regexp.MustCompile(`\$(?P<alias>\w+)\((?P<args>((\s*('(\\'|[^'])*'|\d+(.\d+)?|true|false)\s*,)*(\s*('(\\'|[^'])*'|\d+(.\d+)?|true|false)\s*))|)\)`),
-		inargrx:            regexp.MustCompile(`'(\\'|[^'])*'|\d+(.\d+)?|true|false`),
-		// TODO: postrges uses "" for reserved-word or space-separated col names; handle [] for sql server (and mysql?)
-		outqueryrx:             regexp.MustCompile(`\$(?P<alias>\w+):(?P<col>\w+|"(\w+\s*)+")(?P<full_col_alias>(\s+[aA][sS])?\s+(?P<col_alias>\w+|"(\w+\s*)+")[\s,$])?`),
+		inqueryrx:          regexp.MustCompile(`\$(?P<alias>\w+)\((?P<args>((\s*(` + inarg + `)\s*,)*(\s*(` + inarg + `)\s*))|)\)`),
+		inargrx:            regexp.MustCompile(inarg),
+		// postgres and sqlite use "" for reserved-word or space-separated col names, mysql uses ``, sql server uses []
+		outqueryrx:             regexp.MustCompile(`\$(?P<alias>\w+):(?P<col>` + ident + `)(?P<full_col_alias>(\s+[aA][sS])?\s+(?P<col_alias>` + ident + `)[\s,$])?`),
 		normalizeInMessageArgs: normalizer,
 	}
 }
 
-func (p *queryParser) parse(q string) (string, [][]byte, map[string]func([]byte) (string, error), error) {
+func (p *queryParser) parse(q string) (string, [][]byte, map[string]func([]byte) (fmt.Stringer, error), error) {
 	var inMessageArgs [][]byte
-	var outMessageStringers map[string]func([]byte) (string, error)
+	var outMessageStringers map[string]func([]byte) (fmt.Stringer, error)
 	var err error
 
 	if q, inMessageArgs, err = p.parseInMessageArgs(q); err != nil {
@@ -105,16 +126,33 @@ func (p *queryParser) parseInMessageArgs(q string) (string, [][]byte, error) {
 	return query, queryArgs, nil
 }
 
-func (p *queryParser) parseOutMessageArgs(q string) (string, map[string]func([]byte) (string, error), error) {
+func (p *queryParser) parseOutMessageArgs(q string) (string, map[string]func([]byte) (fmt.Stringer, error), error) {
 	var err error
-	var stringers map[string]func([]byte) (string, error)
+	var stringers map[string]func([]byte) (fmt.Stringer, error)
 	if p.autoMapOutMessages {
 		if stringers, err = p.makeAutoOutMessageStringers(); err != nil {
 			return "", nil, err
 		}
 	} else {
-		stringers = map[string]func([]byte) (string, error){}
+		stringers = map[string]func([]byte) (fmt.Stringer, error){}
+	}
+
+	q, outMessages, err := p.parseOutMessageCols(q)
+	if err != nil {
+		return "", nil, err
 	}
+	for key, outMessage := range outMessages {
+		if stringers[key], err = p.protos.StringerFor(outMessage); err != nil {
+			return "", nil, err
+		}
+	}
+	return q, stringers, nil
+}
+
+// parseOutMessageCols strips out-message aliases from q, and returns the out-messages keyed by the names of the columns they decode.
+func (p *queryParser) parseOutMessageCols(q string) (string, map[string]*config.OutMessage, error) {
+	var err error
+	res := map[string]*config.OutMessage{}
 
 	q = rx.ReplaceAllGroupsFunc(p.outqueryrx, q, func(groups map[string]string) string {
 		if err != nil {
@@ -137,13 +175,12 @@ func (p *queryParser) parseOutMessageArgs(q string) (string, map[string]func([]b
 		} else {
 			key = col
 		}
+		key = strings.Trim(key, "\"`[]") // the DB reports col names unquoted
 
 		// mapping is by col name, not col order (plus auto-mapping can only be done by col name)
 		// this might be an issue in case there are multiple cols with the same name because of aliasing with AS
 		// select *, $p:dat - currently will pretty-print both "dat" cols
-		if stringers[key], err = p.protos.StringerFor(outMessage); err != nil {
-			return ""
-		}
+		res[key] = outMessage
 
 		return col + groups["full_col_alias"]
 	})
@@ -151,12 +188,12 @@ func (p *queryParser) parseOutMessageArgs(q string) (string, map[string]func([]b
 	if err != nil {
 		return "", nil, err
 	}
-	return q, stringers, nil
+	return q, res, nil
 }
 
-func (p *queryParser) makeAutoOutMessageStringers() (map[string]func([]byte) (string, error), error) {
+func (p *queryParser) makeAutoOutMessageStringers() (map[string]func([]byte) (fmt.Stringer, error), error) {
 	var err error
-	stringers := map[string]func([]byte) (string, error){}
+	stringers := map[string]func([]byte) (fmt.Stringer, error){}
 	for alias, outMessage := range p.outMessages {
 		if stringers[alias], err = p.protos.StringerFor(outMessage); err != nil {
 			return nil, err
@@ -43,6 +43,10 @@ func TestQueryParserNormalizeInMessageArgs(t *testing.T) {
 			args:         []string{"'foo bar'", "' bar baz '"},
 			expectedArgs: []string{"\"foo bar\"", "\" bar baz \""},
 		},
+		{
+			args:         []string{"['foo', 'o\\'bar']", "[1, 2]"},
+			expectedArgs: []string{"[\"foo\", \"o'bar\"]", "[1, 2]"},
+		},
 		{
 			args:         []string{"'o\\'foo o\\'bar'", "' bar o\\'baz '"},
 			expectedArgs: []string{"\"o'foo o'bar\"", "\" bar o'baz \""},
@@ -92,6 +96,24 @@ func TestQueryParserParse(t *testing.T) {
 			query:         " select  $foo:foo_col   from test where  bar_col  = $bar( 2 , 'two') ;  ",
 			expectedQuery: " select  foo_col   from test where  bar_col  = $1 ;  ",
 		},
+		{
+			desc:          "valid input, sqlite",
+			driver:        DriverSQLite,
+			query:         "select $foo:foo_col from test where bar_col = $bar(2, 'two') or baz_col = $bar(3, 'three');",
+			expectedQuery: "select foo_col from test where bar_col = ? or baz_col = ?;",
+		},
+		{
+			desc:          "valid input, mysql",
+			driver:        DriverMySQL,
+			query:         "select $foo:`foo col` from test where bar_col = $bar(2, 'two') or baz_col = $bar(3, 'three');",
+			expectedQuery: "select `foo col` from test where bar_col = ? or baz_col = ?;",
+		},
+		{
+			desc:          "valid input, sqlserver",
+			driver:        DriverSQLServer,
+			query:         "select $foo:[foo col] from test where bar_col = $bar(2, 'two') or baz_col = $bar(3, 'three');",
+			expectedQuery: "select [foo col] from test where bar_col = @p1 or baz_col = @p2;",
+		},
 		{
 			desc:   `invalid, unknown "in" alias`,
 			driver: DriverPostgres,
@@ -240,6 +262,39 @@ func TestQueryParserParseInMessageArgs(t *testing.T) {
 			expectedQuery:    "select * from test where foo_col = $1",
 			expectedArgCount: 1,
 		},
+		{
+			desc:             "valid, array args",
+			driver:           DriverPostgres,
+			query:            "select * from test where qux_col = $qux([1, 2,3], 'one')",
+			expectedQuery:    "select * from test where qux_col = $1",
+			expectedArgCount: 1,
+		},
+		{
+			desc:             "valid, empty array arg",
+			driver:           DriverPostgres,
+			query:            "select * from test where qux_col = $qux([ ], 'one')",
+			expectedQuery:    "select * from test where qux_col = $1",
+			expectedArgCount: 1,
+		},
+		{
+			desc:             "valid, array args, multiple args",
+			driver:           DriverPostgres,
+			query:            "select * from test where qux_col = $qux([1], 'one') and foo_col = $foo(1, 'one', true)",
+			expectedQuery:    "select * from test where qux_col = $1 and foo_col = $2",
+			expectedArgCount: 2,
+		},
+		{
+			desc:   "invalid, array of wrong type",
+			driver: DriverPostgres,
+			query:  "select * from test where qux_col = $qux(['a', 'b'], 'one')",
+			err:    true,
+		},
+		{
+			desc:   "invalid, array instead of scalar",
+			driver: DriverPostgres,
+			query:  "select * from test where qux_col = $qux([1], [2])",
+			err:    true,
+		},
 		{
 			desc:   "invalid, JSON-unescaped double quote",
 			driver: DriverPostgres,
@@ -423,6 +478,36 @@ func TestQueryParserParseOutMessageArgs(t *testing.T) {
 				"QuX_Col": {},
 			},
 		},
+		{
+			desc:          "valid, quoted args",
+			driver:        DriverPostgres,
+			query:         `select $foo:"foo col", $bar:bar_col as "Bar Col" from test`,
+			expectedQuery: `select "foo col", bar_col as "Bar Col" from test`,
+			expectedStringerKeys: map[string]struct{}{
+				"foo col": {},
+				"Bar Col": {},
+			},
+		},
+		{
+			desc:          "valid, backtick-quoted args, mysql",
+			driver:        DriverMySQL,
+			query:         "select $foo:`foo col`, $bar:`select` as `bar col` from test",
+			expectedQuery: "select `foo col`, `select` as `bar col` from test",
+			expectedStringerKeys: map[string]struct{}{
+				"foo col": {},
+				"bar col": {},
+			},
+		},
+		{
+			desc:          "valid, bracketed args, sqlserver",
+			driver:        DriverSQLServer,
+			query:         "select $foo:[foo col], $bar:[select] as [bar col] from test",
+			expectedQuery: "select [foo col], [select] as [bar col] from test",
+			expectedStringerKeys: map[string]struct{}{
+				"foo col": {},
+				"bar col": {},
+			},
+		},
 		{
 			desc:               "valid, auto-map, plain",
 			driver:             DriverPostgres,
@@ -432,6 +517,7 @@ func TestQueryParserParseOutMessageArgs(t *testing.T) {
 			expectedStringerKeys: map[string]struct{}{
 				"foo": {},
 				"bar": {},
+				"qux": {},
 			},
 		},
 		{
@@ -444,6 +530,7 @@ func TestQueryParserParseOutMessageArgs(t *testing.T) {
 				"foo": {},
 				"bar": {},
 				"baz": {},
+				"qux": {},
 			},
 		},
 		{
@@ -455,6 +542,7 @@ func TestQueryParserParseOutMessageArgs(t *testing.T) {
 			expectedStringerKeys: map[string]struct{}{
 				"foo": {},
 				"bar": {},
+				"qux": {},
 			},
 		},
 		{
@@ -466,6 +554,7 @@ func TestQueryParserParseOutMessageArgs(t *testing.T) {
 			expectedStringerKeys: map[string]struct{}{
 				"foo": {},
 				"bar": {},
+				"qux": {},
 			},
 		},
 		{
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/m18/cpb/config"
+)
+
+const defaultPostgresPort = 5432
+
+// NeedsPassword reports whether the database configured by c may need a password that is not available,
+// i.e., it is neither configured nor, for PostgreSQL, found in the password file.
+func NeedsPassword(c *config.DBConfig) bool {
+	switch {
+	case c.Driver == DriverSQLite || c.Password != "":
+		return false
+	case c.Driver == DriverPostgres:
+		_, ok := pgpassPassword(c)
+		return !ok
+	default:
+		return true
+	}
+}
+
+// pgpassPassword returns the password for c from the PostgreSQL password file, $PGPASSFILE or ~/.pgpass, if any.
+// Like libpq, it ignores the file if it is accessible by group or others.
+//
+// See https://www.postgresql.org/docs/current/libpq-pgpass.html
+func pgpassPassword(c *config.DBConfig) (string, bool) {
+	path := os.Getenv("PGPASSFILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = filepath.Join(home, ".pgpass")
+	}
+	info, err := os.Stat(path)
+	if err != nil || (runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0) {
+		return "", false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	return lookupPgpass(f, c)
+}
+
+// lookupPgpass returns the password of the first line of r, a password file, matching c.
+// Lines are hostname:port:database:username:password, where the first four fields can be *, and \ escapes : and \.
+func lookupPgpass(r io.Reader, c *config.DBConfig) (string, bool) {
+	port := c.Port
+	if port == 0 {
+		port = defaultPostgresPort
+	}
+	values := []string{c.Host, strconv.Itoa(port), c.Name, c.UserName}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPgpassLine(line)
+		if len(fields) != len(values)+1 {
+			continue
+		}
+		matches := true
+		for i, v := range values {
+			if fields[i] != "*" && fields[i] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return fields[len(values)], true
+		}
+	}
+	return "", false
+}
+
+func splitPgpassLine(line string) []string {
+	res := []string{}
+	var field strings.Builder
+	escaped := false
+	for _, c := range line {
+		switch {
+		case escaped:
+			field.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ':':
+			res = append(res, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(c)
+		}
+	}
+	return append(res, field.String())
+}
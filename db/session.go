@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// querier runs statements: either the session's connection or its transaction.
+type querier interface {
+	QueryContext(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, q string, args ...interface{}) (sql.Result, error)
+}
+
+// session returns what to run statements with.
+//
+// All statements run on the same connection, pinned on first use, so that session state,
+// e.g., a transaction started with an explicit BEGIN, carries over from one statement to the next.
+func (d *DB) session(ctx context.Context) (querier, error) {
+	if d.tx != nil {
+		return d.tx, nil
+	}
+	if d.conn == nil {
+		conn, err := d.c.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		d.conn = conn
+	}
+	return d.conn, nil
+}
+
+// InTransaction runs f, which runs statements with d, in a single transaction.
+// The transaction is committed if f succeeds, and rolled back otherwise, including when ctx is done.
+func (d *DB) InTransaction(ctx context.Context, f func() error) error {
+	if d.tx != nil {
+		return errors.New("already in a transaction")
+	}
+	if _, err := d.session(ctx); err != nil {
+		return err
+	}
+	tx, err := d.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	d.tx = tx
+	defer func() { d.tx = nil }()
+	if err := f(); err != nil {
+		if rerr := tx.Rollback(); rerr != nil && !errors.Is(rerr, sql.ErrTxDone) {
+			return fmt.Errorf("%w (could not roll back transaction: %v)", err, rerr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/cpb/internal/testconfig"
+)
+
+func makeTestDBSQLite(t *testing.T) *DB {
+	cfg, err := testconfig.MakeTestConfigLite(DriverSQLite)
+	testcheck.FatalIf(t, err)
+	cfg.DB.Name = filepath.Join(t.TempDir(), "test.db")
+	d, err := New(cfg.DB, nil, nil, nil, false)
+	testcheck.FatalIf(t, err)
+	t.Cleanup(func() { d.Close() })
+	_, err = d.Query(context.Background(), "create table test (id integer);")
+	testcheck.FatalIf(t, err)
+	return d
+}
+
+func testCount(t *testing.T, d *DB, expected int) {
+	t.Helper()
+	res, err := d.Query(context.Background(), "select count(*) from test;")
+	testcheck.FatalIf(t, err)
+	if count := res.Rows[0][0]; count != int64(expected) {
+		t.Fatalf("expected %d rows but there were %v", expected, count)
+	}
+}
+
+func TestDBSessionExplicitTransaction(t *testing.T) {
+	d := makeTestDBSQLite(t)
+	ctx := context.Background()
+	for _, q := range []string{
+		"begin;",
+		"insert into test values (1);",
+		"rollback;",
+		"begin;",
+		"insert into test values (2);",
+		"commit;",
+	} {
+		_, err := d.Query(ctx, q)
+		testcheck.FatalIf(t, err)
+	}
+	testCount(t, d, 1)
+}
+
+func TestDBInTransaction(t *testing.T) {
+	errTest := errors.New("test error")
+	tests := []struct {
+		desc          string
+		f             func(*DB) error
+		expectedErr   error
+		expectedCount int
+	}{
+		{
+			desc: "commit",
+			f: func(d *DB) error {
+				_, err := d.Query(context.Background(), "insert into test values (1), (2);")
+				return err
+			},
+			expectedCount: 2,
+		},
+		{
+			desc: "rollback",
+			f: func(d *DB) error {
+				if _, err := d.Query(context.Background(), "insert into test values (1), (2);"); err != nil {
+					return err
+				}
+				return errTest
+			},
+			expectedErr: errTest,
+		},
+		{
+			desc: "nested",
+			f: func(d *DB) error {
+				return d.InTransaction(context.Background(), func() error { return nil })
+			},
+			expectedErr: errors.New("already in a transaction"),
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			d := makeTestDBSQLite(t)
+			err := d.InTransaction(context.Background(), func() error { return test.f(d) })
+			testcheck.FatalIfUnexpected(t, err, test.expectedErr != nil)
+			if err != nil && err.Error() != test.expectedErr.Error() {
+				t.Fatalf("expected error to be %q but it was %q", test.expectedErr, err)
+			}
+			testCount(t, d, test.expectedCount)
+		})
+	}
+}
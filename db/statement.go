@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// leading comments are skipped
+	keywordrx = regexp.MustCompile(`(?s)^(?:\s+|--[^\n]*(?:\n|$)|/\*.*?\*/)*(\w+)`)
+	// postgres, sqlite and mariadb use RETURNING, sql server uses OUTPUT inserted.*/deleted.*
+	returningrx = regexp.MustCompile(`(?i)\breturning\b|\boutput\s+(inserted|deleted)\.`)
+	// single-quoted strings, e.g., 'returning', with \' escapes, as in in-message args
+	stringrx = regexp.MustCompile(`'(\\'|[^'])*'`)
+)
+
+// execKeywords are the leading keywords of the statements that modify rows rather than return them.
+var execKeywords = map[string]struct{}{
+	"INSERT":  {},
+	"UPDATE":  {},
+	"DELETE":  {},
+	"MERGE":   {},
+	"REPLACE": {},
+}
+
+// keyword returns the leading keyword of q, upper-cased, e.g., UPDATE.
+func keyword(q string) string {
+	m := keywordrx.FindStringSubmatch(q)
+	if m == nil {
+		return ""
+	}
+	return strings.ToUpper(m[1])
+}
+
+// isExec returns whether q, which starts with kw, modifies rows without returning any, and so has rows affected.
+func isExec(kw, q string) bool {
+	if _, ok := execKeywords[kw]; !ok {
+		return false
+	}
+	return !returningrx.MatchString(stringrx.ReplaceAllString(q, "''"))
+}
+
+// status returns the status of a statement that starts with kw and returns no rows, e.g., UPDATE 3.
+//
+// rowsAffected is negative if it is not known.
+func status(kw string, rowsAffected int64) string {
+	if rowsAffected < 0 {
+		return kw
+	}
+	return fmt.Sprintf("%s %d", kw, rowsAffected)
+}
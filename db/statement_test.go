@@ -0,0 +1,61 @@
+package db
+
+import "testing"
+
+func TestKeyword(t *testing.T) {
+	tests := []struct {
+		q        string
+		expected string
+	}{
+		{q: "select 1;", expected: "SELECT"},
+		{q: "  Update foo set bar = 1;", expected: "UPDATE"},
+		{q: "-- foo\n/* bar\n baz */ delete from foo;", expected: "DELETE"},
+		{q: "", expected: ""},
+		{q: "-- foo", expected: ""},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.q, func(t *testing.T) {
+			t.Parallel()
+			if res := keyword(test.q); res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestIsExec(t *testing.T) {
+	tests := []struct {
+		q        string
+		expected bool
+	}{
+		{q: "insert into foo values (1);", expected: true},
+		{q: "update foo set bar = 1;", expected: true},
+		{q: "delete from foo;", expected: true},
+		{q: "insert into foo values ('returning', 'it\\'s returning');", expected: true},
+		{q: "insert into foo values (1) returning id;"},
+		{q: "delete from foo RETURNING *;"},
+		{q: "insert into foo output inserted.id values (1);"},
+		{q: "select * from foo;"},
+		{q: "with x as (delete from foo returning *) select * from x;"},
+		{q: "create table foo (id int);"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.q, func(t *testing.T) {
+			t.Parallel()
+			if res := isExec(keyword(test.q), test.q); res != test.expected {
+				t.Fatalf("expected %v but got %v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	if res := status("UPDATE", 3); res != "UPDATE 3" {
+		t.Fatalf("expected %q but got %q", "UPDATE 3", res)
+	}
+	if res := status("CREATE", -1); res != "CREATE" {
+		t.Fatalf("expected %q but got %q", "CREATE", res)
+	}
+}
@@ -0,0 +1,49 @@
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// AnyDirs is the pattern segment matching zero or more directories, e.g., `google/**/*.proto`.
+const AnyDirs = "**"
+
+// Match reports whether the slash-separated name matches the pattern.
+//
+// The pattern syntax is that of path.Match, plus AnyDirs segments.
+func Match(pattern, name string) (bool, error) {
+	return match(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Validate returns path.ErrBadPattern if the pattern is malformed.
+func Validate(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func match(patterns, names []string) (bool, error) {
+	for len(patterns) > 0 {
+		if patterns[0] == AnyDirs {
+			for i := 0; i <= len(names); i++ {
+				ok, err := match(patterns[1:], names[i:])
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(names) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(patterns[0], names[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0, nil
+}
@@ -0,0 +1,68 @@
+package glob
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+		err      bool
+	}{
+		{pattern: "foo.proto", name: "foo.proto", expected: true},
+		{pattern: "foo.proto", name: "bar.proto"},
+		{pattern: "*.proto", name: "foo.proto", expected: true},
+		{pattern: "*.proto", name: "foo/bar.proto"},
+		{pattern: "foo/*.proto", name: "foo/bar.proto", expected: true},
+		{pattern: "foo/*", name: "foo/bar/baz.proto"},
+		{pattern: "**", name: "foo.proto", expected: true},
+		{pattern: "**", name: "foo/bar/baz.proto", expected: true},
+		{pattern: "**/*.proto", name: "foo.proto", expected: true},
+		{pattern: "**/*.proto", name: "foo/bar/baz.proto", expected: true},
+		{pattern: "foo/**", name: "foo/bar/baz.proto", expected: true},
+		{pattern: "foo/**", name: "bar/baz.proto"},
+		{pattern: "foo/**/baz.proto", name: "foo/baz.proto", expected: true},
+		{pattern: "foo/**/baz.proto", name: "foo/bar/qux/baz.proto", expected: true},
+		{pattern: "foo/**/baz.proto", name: "foo/bar/qux.proto"},
+		{pattern: "google/**/v?/*.proto", name: "google/api/expr/v1/syntax.proto", expected: true},
+		{pattern: "[", name: "foo.proto", err: true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(fmt.Sprintf("%s, %s", test.pattern, test.name), func(t *testing.T) {
+			t.Parallel()
+			res, err := Match(test.pattern, test.name)
+			if (err != nil) != test.err {
+				t.Fatalf("expected error: %t, but got %v", test.err, err)
+			}
+			if res != test.expected {
+				t.Fatalf("expected %t but got %t", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		pattern string
+		err     bool
+	}{
+		{pattern: "foo.proto"},
+		{pattern: "**/*.proto"},
+		{pattern: "foo/[a-z]*.proto"},
+		{pattern: "[", err: true},
+		{pattern: "foo/[a-/bar", err: true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.pattern, func(t *testing.T) {
+			t.Parallel()
+			if err := Validate(test.pattern); (err != nil) != test.err {
+				t.Fatalf("expected error: %t, but got %v", test.err, err)
+			}
+		})
+	}
+}
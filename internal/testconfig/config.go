@@ -44,6 +44,15 @@ const testConfigFormat = `{
 			"empty()": {
 				"name": "testproto.lite.Foo",
 				"template": {}
+			},
+			"qux(ids, names)": {
+				"name": "testproto.lite.Lists",
+				"template": {
+					"ids": "$ids",
+					"labels": {
+						"names": "$names"
+					}
+				}
 			}
 		},
 		"out": {
@@ -52,6 +61,10 @@ const testConfigFormat = `{
 			},
 			"bar": {
 				"name": "testproto.lite.nested.Bar"
+			},
+			"qux": {
+				"name": "testproto.lite.Lists",
+				"template": "$ids[0] ($ids[*]): $labels[\"names\"]"
 			}
 		}
 	}
@@ -1,8 +1,68 @@
 package tmpl
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// IndexAll is the index that fans out to all items of a repeated field or a map, e.g., `phones[*]`.
+	IndexAll = "*"
+
+	indexValue = `\d+|true|false|\*|"(?:\\.|[^"\\])*"`
+	index      = `\[(?:` + indexValue + `)\]`
+
+	// PropPattern is a regular expression matching template properties, e.g., `foo`, `foo.bar`, `foo[0].bar`, `foo[*]`, `foo[true]`, `foo["bar"].baz`.
+	PropPattern = `(?:\w+(?:` + index + `)?\.)*\w+(?:` + index + `)?`
+)
+
+var segmentrx = regexp.MustCompile(`^(\w+)(?:\[(` + indexValue + `)\])?(?:\.|$)`)
+
+// Segment is a single property accessor of a template property, e.g., `phones[0]` in `phones[0].number`.
+type Segment struct {
+	Name  string
+	Index string // "" if the property is not indexed, IndexAll, a number, a bool, or a quoted string
+}
+
+// IsIndexed reports whether the segment has an index.
+func (s Segment) IsIndexed() bool {
+	return s.Index != ""
+}
+
+// Segments splits prop into its property accessors.
+func Segments(prop string) ([]Segment, error) {
+	res := []Segment{}
+	for rest := prop; rest != ""; {
+		m := segmentrx.FindStringSubmatch(rest)
+		if m == nil || (len(m[0]) == len(rest) && strings.HasSuffix(rest, ".")) {
+			return nil, fmt.Errorf("invalid property: %s", prop)
+		}
+		res = append(res, Segment{Name: m[1], Index: m[2]})
+		rest = rest[len(m[0]):]
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("invalid property: %s", prop)
+	}
+	return res, nil
+}
 
 // PropToTemplateParam replaces every occurrence of "." with "_" inside prop so that the resulting string could be used as a map key param in a template.
+//
+// Props with indexes (e.g., `phones[0].number`) cannot be template identifiers and are returned as-is.
 func PropToTemplateParam(prop string) string {
+	if strings.Contains(prop, "[") {
+		return prop
+	}
 	return strings.ReplaceAll(prop, ".", "_")
 }
+
+// PropToTemplateAction returns a template action that outputs the value of the map key param for prop.
+func PropToTemplateAction(prop string) string {
+	param := PropToTemplateParam(prop)
+	if strings.Contains(prop, "[") {
+		return "{{index . " + strconv.Quote(param) + "}}"
+	}
+	return "{{." + param + "}}"
+}
@@ -1,6 +1,10 @@
 package tmpl
 
-import "testing"
+import (
+	"fmt"
+	"regexp"
+	"testing"
+)
 
 func TestPropToTemplateParam(t *testing.T) {
 	tests := []struct {
@@ -11,6 +15,8 @@ func TestPropToTemplateParam(t *testing.T) {
 		{input: "foo", expected: "foo"},
 		{input: "foo.bar", expected: "foo_bar"},
 		{input: "...", expected: "___"},
+		{input: "foo[0].bar", expected: "foo[0].bar"},
+		{input: `foo["a.b"]`, expected: `foo["a.b"]`},
 	}
 	for _, test := range tests {
 		test := test
@@ -23,3 +29,105 @@ func TestPropToTemplateParam(t *testing.T) {
 		})
 	}
 }
+
+func TestPropToTemplateAction(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "foo", expected: "{{.foo}}"},
+		{input: "foo.bar", expected: "{{.foo_bar}}"},
+		{input: "foo[*]", expected: `{{index . "foo[*]"}}`},
+		{input: `foo["bar"].baz`, expected: `{{index . "foo[\"bar\"].baz"}}`},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.input, func(t *testing.T) {
+			t.Parallel()
+			res := PropToTemplateAction(test.input)
+			if res != test.expected {
+				t.Errorf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Segment
+		err      bool
+	}{
+		{input: "", err: true},
+		{input: ".", err: true},
+		{input: "foo.", err: true},
+		{input: ".foo", err: true},
+		{input: "foo..bar", err: true},
+		{input: "foo[]", err: true},
+		{input: "foo[x]", err: true},
+		{input: "foo[0]bar", err: true},
+		{
+			input:    "foo",
+			expected: []Segment{{Name: "foo"}},
+		},
+		{
+			input:    "foo.bar",
+			expected: []Segment{{Name: "foo"}, {Name: "bar"}},
+		},
+		{
+			input:    "foo[0].bar[*]",
+			expected: []Segment{{Name: "foo", Index: "0"}, {Name: "bar", Index: IndexAll}},
+		},
+		{
+			input:    "foo[true].bar[false]",
+			expected: []Segment{{Name: "foo", Index: "true"}, {Name: "bar", Index: "false"}},
+		},
+		{
+			input:    `foo["a.b[\"c\"]"].bar`,
+			expected: []Segment{{Name: "foo", Index: `"a.b[\"c\"]"`}, {Name: "bar"}},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.input, func(t *testing.T) {
+			t.Parallel()
+			res, err := Segments(test.input)
+			if (err != nil) != test.err {
+				t.Fatalf("expected error: %t, got: %v", test.err, err)
+			}
+			if fmt.Sprint(res) != fmt.Sprint(test.expected) {
+				t.Fatalf("expected %v but got %v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestPropPattern(t *testing.T) {
+	rx := regexp.MustCompile(`^` + PropPattern + `$`)
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "foo", expected: true},
+		{input: "foo.bar", expected: true},
+		{input: "foo[0].bar", expected: true},
+		{input: "foo[*]", expected: true},
+		{input: `foo["bar"].baz`, expected: true},
+		{input: `foo["b\"ar"]`, expected: true},
+		{input: "foo[true].bar", expected: true},
+		{input: "foo.", expected: false},
+		{input: "foo[]", expected: false},
+		{input: "foo[-1]", expected: false},
+		{input: `foo[bar]`, expected: false},
+		{input: `foo[True]`, expected: false},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.input, func(t *testing.T) {
+			t.Parallel()
+			if res := rx.MatchString(test.input); res != test.expected {
+				t.Fatalf("expected %t but got %t", test.expected, res)
+			}
+		})
+	}
+}
@@ -1,30 +1,44 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"fmt"
+	"io"
 	"os"
 
+	"github.com/m18/cpb/codec"
 	"github.com/m18/cpb/config"
 	"github.com/m18/cpb/db"
+	"github.com/m18/cpb/meta"
 	"github.com/m18/cpb/printer"
 	"github.com/m18/cpb/protos"
+	"github.com/m18/cpb/repl"
+	"github.com/m18/cpb/script"
 	"github.com/m18/cpb/sys"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
-// TODO: add commands at root, e.g., config to print config
-
 func main() {
 	cfg, err := config.New(os.Args[1:], os.DirFS)
 	sys.ExitIf(err)
 
-	args, pipe, err := querySources(cfg)
+	p, err := protos.New(cfg.Proto, os.DirFS, nil, false)
 	sys.ExitIf(err)
-	if !args && !pipe {
+
+	if cfg.Command != nil {
+		// offline subcommands do not need the database
+		err = codec.Run(cfg, p, os.Stdin, os.Stdout)
+		sys.ExitIf(err)
 		return
 	}
 
-	p, err := protos.New(cfg.Proto.C, cfg.Proto.Dir, cfg.Proto.Deterministic, os.DirFS, nil, false)
+	args, file, pipe, err := querySources(cfg)
+	sys.ExitIf(err)
+	interactive := !args && !file && !pipe
+	// meta-commands do not need the database
+	commandOnly := args && !file && !pipe && meta.IsCommand(cfg.DB.Query)
+
+	err = resolvePassword(cfg, commandOnly)
 	sys.ExitIf(err)
 
 	db, err := db.New(cfg.DB, p, cfg.InMessages, cfg.OutMessages, cfg.AutoMapOutMessages)
@@ -32,55 +46,202 @@ func main() {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	sys.HandleInterrupt(ctx, cancel)
-
-	err = db.Ping(ctx)
-	sys.ExitIf(err, ctx.Err())
+	if !interactive {
+		sys.HandleInterrupt(ctx, cancel)
+	}
 
-	// TODO: config
 	pr, err := printer.New(
 		os.Stdout,
-		printer.WithFormat(printer.FormatTable),
-		printer.WithHeader(true),
-		printer.WithSpacing(1),
+		printer.WithFormat(printer.Format(cfg.Output.Format)),
+		printer.WithHeader(cfg.Output.Header),
+		printer.WithSpacing(cfg.Output.Spacing),
 	)
 	sys.ExitIf(err)
 
-	if args {
-		err = queryAndPrint(ctx, db, cfg.DB.Query, pr)
-		sys.ExitIf(err, ctx.Err())
+	e := &executor{
+		db:     db,
+		cmds:   meta.New(cfg, p, pr, os.Stdout),
+		pr:     pr,
+		dryRun: cfg.Script.DryRun,
+	}
+	if commandOnly {
+		err = e.run(ctx, cfg.DB.Query)
+		sys.ExitIf(err)
+		return
 	}
 
-	if pipe {
-		err = queryFromPipe(ctx, db, pr)
+	if !e.dryRun {
+		err = db.Ping(ctx)
 		sys.ExitIf(err, ctx.Err())
 	}
+
+	if interactive {
+		err = runREPL(ctx, e, newCompleter(ctx, cfg, p, e))
+		sys.ExitIf(err)
+		return
+	}
+
+	run := func() error {
+		if args {
+			if err := e.run(ctx, cfg.DB.Query); err != nil {
+				return err
+			}
+		}
+		if file {
+			if err := queryFromFile(ctx, e, cfg.Script); err != nil {
+				return err
+			}
+		}
+		if pipe {
+			return queryFromScript(ctx, e, os.Stdin, cfg.Script.OnError)
+		}
+		return nil
+	}
+	if cfg.Script.SingleTransaction && !e.dryRun {
+		err = db.InTransaction(ctx, run)
+	} else {
+		err = run()
+	}
+	sys.ExitIf(err, ctx.Err())
 }
 
-func querySources(cfg *config.Config) (args, pipe bool, err error) {
+func querySources(cfg *config.Config) (args, file, pipe bool, err error) {
 	args = cfg.DB.Query != ""
+	file = cfg.Script.File != ""
 	pipe, err = sys.IsPipedIn()
-	return args, pipe, err
+	return args, file, pipe, err
 }
 
-func queryFromPipe(ctx context.Context, db *db.DB, pr *printer.Printer) error {
-	s := bufio.NewScanner(os.Stdin)
-	for s.Scan() {
-		if err := queryAndPrint(ctx, db, s.Text(), pr); err != nil {
-			return err
-		}
+// resolvePassword runs the password command, then prompts for the password if it is still missing,
+// unless the database is not used, i.e., with --dry-run or when only meta-commands are passed as arguments.
+// It is not part of config validation, which cannot tell the latter, as it depends on whether statements are piped in.
+func resolvePassword(cfg *config.Config, commandOnly bool) error {
+	if commandOnly || cfg.Script.DryRun {
+		return nil
+	}
+	if err := cfg.DB.RunPasswordCommand(); err != nil {
+		return err
+	}
+	return promptPassword(cfg.DB)
+}
+
+// promptPassword prompts for the password if it is missing and stdin is a terminal.
+func promptPassword(cfg *config.DBConfig) error {
+	if !db.NeedsPassword(cfg) {
+		return nil
+	}
+	if !sys.IsTerminal() || !repl.TerminalSupported() {
+		return nil
+	}
+	password, err := repl.PromptPassword(fmt.Sprintf("Password for %s: ", cfg.UserName))
+	if err != nil {
+		return err
 	}
+	cfg.Password = password
 	return nil
 }
 
-func queryAndPrint(ctx context.Context, db *db.DB, q string, pr *printer.Printer) error {
+func queryFromFile(ctx context.Context, e *executor, cfg *config.Script) error {
+	f, err := os.Open(cfg.File)
+	if err != nil {
+		return fmt.Errorf("could not open script: %w", err)
+	}
+	defer f.Close()
+	if err := queryFromScript(ctx, e, f, cfg.OnError); err != nil {
+		return fmt.Errorf("%s: %w", cfg.File, err)
+	}
+	return nil
+}
+
+func queryFromScript(ctx context.Context, e *executor, r io.Reader, onError string) error {
+	exec := func(q string) error {
+		return e.run(ctx, q)
+	}
+	return script.Run(ctx, r, exec, onError == config.OnErrorContinue, os.Stdout)
+}
+
+func runREPL(ctx context.Context, e *executor, c *repl.Completer) (err error) {
+	historyPath, err := repl.DefaultHistoryPath()
+	if err != nil {
+		historyPath = "" // no history is better than no REPL
+	}
+	r, err := repl.New(historyPath, os.Stdout, c)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := r.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return r.Run(func(q string) error {
+		// Ctrl-C cancels the statement being executed, not the REPL
+		qctx, qcancel := context.WithCancel(ctx)
+		defer qcancel()
+		sys.HandleInterrupt(qctx, qcancel)
+		return e.run(qctx, q)
+	})
+}
+
+func newCompleter(ctx context.Context, cfg *config.Config, p *protos.Protos, e *executor) *repl.Completer {
+	var tables map[string][]string
+	if !e.dryRun {
+		var err error
+		if tables, err = e.db.Catalog(ctx); err != nil {
+			tables = nil // no table completion is better than no REPL
+		}
+	}
+	res := &repl.Completer{
+		InAliases: make(map[string][]string, len(cfg.InMessages)),
+		Tables:    tables,
+		FieldPaths: func(message string) []string {
+			paths, _ := p.FieldPaths(protoreflect.FullName(message)) // message comes from p.Messages()
+			return paths
+		},
+	}
+	for alias, m := range cfg.InMessages {
+		res.InAliases[alias] = m.Params()
+	}
+	for alias := range cfg.OutMessages {
+		res.OutAliases = append(res.OutAliases, alias)
+	}
+	for _, message := range p.Messages() {
+		res.Messages = append(res.Messages, string(message))
+	}
+	return res
+}
+
+// executor runs statements and meta-commands, and prints their results.
+type executor struct {
+	db     *db.DB
+	cmds   *meta.Commands
+	pr     *printer.Printer
+	dryRun bool // see config.Script.DryRun
+}
+
+func (e *executor) run(ctx context.Context, q string) error {
 	if q == "" {
 		return nil
 	}
-	cols, rows, err := db.Query(ctx, q)
+	if meta.IsCommand(q) {
+		return e.cmds.Run(q)
+	}
+	if e.dryRun {
+		res, err := e.db.Explain(q)
+		if err != nil {
+			return err
+		}
+		fmt.Print(res)
+		return nil
+	}
+	res, err := e.db.Query(ctx, q)
 	if err != nil {
 		return err
 	}
-	pr.Print(cols, rows)
+	if res.Status != "" {
+		e.pr.PrintStatus(res.Status)
+		return nil
+	}
+	e.pr.Print(res.Cols, res.Rows)
 	return nil
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/internal/testcheck"
+)
+
+func TestResolvePassword(t *testing.T) {
+	tests := []struct {
+		desc        string
+		commandOnly bool
+		dryRun      bool
+		command     string
+		expected    string
+		err         bool
+	}{
+		{
+			desc:     "database used",
+			command:  "echo secret",
+			expected: "secret",
+		},
+		{
+			desc:        "meta-commands only",
+			commandOnly: true,
+			command:     "exit 1",
+		},
+		{
+			desc:    "dry run",
+			dryRun:  true,
+			command: "exit 1",
+		},
+		{
+			desc:    "failed command",
+			command: "exit 1",
+			err:     true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			cfg := &config.Config{
+				DB:     &config.DBConfig{Driver: "postgres", UserName: "foo", PasswordCommand: test.command},
+				Script: &config.Script{DryRun: test.dryRun},
+			}
+			err := resolvePassword(cfg, test.commandOnly)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if cfg.DB.Password != test.expected {
+				t.Fatalf("expected password to be %q but it was %q", test.expected, cfg.DB.Password)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package meta
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/printer"
+	"github.com/m18/cpb/protos"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	prefix     = `\`
+	terminator = ";"
+
+	kindIn  = "in"
+	kindOut = "out"
+)
+
+// Commands runs meta-commands: backslash-prefixed commands, e.g., `\aliases`, that introspect cpb rather than query the database.
+type Commands struct {
+	cfg  *config.Config
+	p    *protos.Protos
+	pr   *printer.Printer
+	w    io.Writer
+	cmds map[string]func(args []string) error
+}
+
+// New returns new Commands which print tabular results with pr and text to w.
+func New(cfg *config.Config, p *protos.Protos, pr *printer.Printer, w io.Writer) *Commands {
+	res := &Commands{
+		cfg: cfg,
+		p:   p,
+		pr:  pr,
+		w:   w,
+	}
+	res.cmds = map[string]func([]string) error{
+		"aliases":  res.aliases,
+		"describe": res.describe,
+		"config":   res.config,
+	}
+	return res
+}
+
+// IsCommand returns whether s is a meta-command rather than a query.
+func IsCommand(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), prefix)
+}
+
+// Run runs meta-command s, e.g., `\describe example.Employee`. A trailing semicolon is ignored.
+func (c *Commands) Run(s string) error {
+	fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(s), terminator))
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], prefix) {
+		return fmt.Errorf("not a command: %q", s)
+	}
+	cmd, ok := c.cmds[strings.TrimPrefix(fields[0], prefix)]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", fields[0])
+	}
+	return cmd(fields[1:])
+}
+
+// aliases prints the in- and out-message aliases along with their parameters and message names.
+func (c *Commands) aliases(args []string) error {
+	if err := argCount(`\aliases`, args, 0); err != nil {
+		return err
+	}
+	rows := [][]interface{}{}
+	for alias, m := range c.cfg.InMessages {
+		rows = append(rows, []interface{}{kindIn, alias, strings.Join(m.Params(), ", "), string(m.Name)})
+	}
+	for alias, m := range c.cfg.OutMessages {
+		rows = append(rows, []interface{}{kindOut, alias, "", string(m.Name)})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i][0] != rows[j][0] {
+			return rows[i][0] == kindIn
+		}
+		return rows[i][1].(string) < rows[j][1].(string)
+	})
+	c.pr.Print([]string{"kind", "alias", "params", "message"}, rows)
+	return nil
+}
+
+// describe prints the fields and nested enums of a message.
+func (c *Commands) describe(args []string) error {
+	if err := argCount(`\describe`, args, 1); err != nil {
+		return err
+	}
+	res, err := c.p.Describe(protoreflect.FullName(args[0]))
+	if err != nil {
+		return fmt.Errorf("could not describe %q: %w", args[0], err)
+	}
+	fmt.Fprintln(c.w, res)
+	return nil
+}
+
+// config prints the effective configuration.
+func (c *Commands) config(args []string) error {
+	if err := argCount(`\config`, args, 0); err != nil {
+		return err
+	}
+	res, err := c.cfg.JSON()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.w, res)
+	return nil
+}
+
+func argCount(cmd string, args []string, expected int) error {
+	if len(args) != expected {
+		return fmt.Errorf("%s expects %d argument(s) but got %d", cmd, expected, len(args))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package meta
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/cpb/internal/testconfig"
+	"github.com/m18/cpb/internal/testproto"
+	"github.com/m18/cpb/printer"
+	"github.com/m18/cpb/protos"
+)
+
+func makeTestCommands(t *testing.T) (*Commands, *bytes.Buffer) {
+	cfg, err := testconfig.MakeTestConfigLite("postgres")
+	testcheck.FatalIf(t, err)
+	p, err := protos.New(
+		&config.Proto{C: config.ProtocBuiltin, Dir: testproto.DirLite},
+		testproto.MakeFS,
+		testproto.MakeFileReg(),
+		testproto.Mute,
+	)
+	testcheck.FatalIf(t, err)
+	w := &bytes.Buffer{}
+	pr, err := printer.New(w, printer.WithFormat(printer.FormatCSV), printer.WithHeader(true))
+	testcheck.FatalIf(t, err)
+	return New(cfg, p, pr, w), w
+}
+
+func TestIsCommand(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected bool
+	}{
+		{s: `\aliases`, expected: true},
+		{s: ` \config;`, expected: true},
+		{s: "select 1;"},
+		{s: ""},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.s, func(t *testing.T) {
+			t.Parallel()
+			if res := IsCommand(test.s); res != test.expected {
+				t.Fatalf("expected %v but got %v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestCommandsRun(t *testing.T) {
+	tests := []struct {
+		desc     string
+		s        string
+		expected []string // substrings of the output
+		err      bool
+	}{
+		{
+			desc: "aliases",
+			s:    `\aliases`,
+			expected: []string{
+				"kind,alias,params,message\n" +
+					"in,bar,\"id, name\",testproto.lite.nested.Bar\n" +
+					"in,empty,,testproto.lite.Foo\n" +
+					"in,foo,\"id, text, on\",testproto.lite.Foo\n" +
+					"in,qux,\"ids, names\",testproto.lite.Lists\n" +
+					"out,bar,,testproto.lite.nested.Bar\n" +
+					"out,foo,,testproto.lite.Foo\n" +
+					"out,qux,,testproto.lite.Lists\n",
+			},
+		},
+		{
+			desc:     "describe",
+			s:        `\describe testproto.lite.nested.Bar;`,
+			expected: []string{"message testproto.lite.nested.Bar {\n", "    testproto.lite.nested.Bar.Baz nested = 3;\n", "    enum Qux {\n"},
+		},
+		{
+			desc:     "config",
+			s:        ` \config `,
+			expected: []string{`"driver": "postgres"`, `"password": "********"`, `"foo(id, text, on)": {`},
+		},
+		{
+			desc: "unknown command",
+			s:    `\foo`,
+			err:  true,
+		},
+		{
+			desc: "not a command",
+			s:    "select 1;",
+			err:  true,
+		},
+		{
+			desc: "wrong argument count",
+			s:    `\aliases foo`,
+			err:  true,
+		},
+		{
+			desc: "no argument",
+			s:    `\describe`,
+			err:  true,
+		},
+		{
+			desc: "unknown message",
+			s:    `\describe foo.Bar`,
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			c, w := makeTestCommands(t)
+			err := c.Run(test.s)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			for _, s := range test.expected {
+				if !strings.Contains(w.String(), s) {
+					t.Fatalf("expected %q to contain %q but it did not", w.String(), s)
+				}
+			}
+		})
+	}
+}
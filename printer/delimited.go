@@ -0,0 +1,76 @@
+package printer
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	commaCSV = ','
+	commaTSV = '\t'
+
+	bytesPrefix = `\x`
+)
+
+// delimitedFormatter writes rows as delimiter-separated values.
+//
+// Fields are quoted according to RFC 4180, i.e., only when they contain the delimiter, a double quote, or a line break,
+// with double quotes escaped by doubling them. Records are terminated by \n rather than \r\n to play well with line-oriented tools.
+type delimitedFormatter struct {
+	header bool
+	comma  rune
+}
+
+func newCSVFormatter(header bool) *delimitedFormatter {
+	return &delimitedFormatter{
+		header: header,
+		comma:  commaCSV,
+	}
+}
+
+func newTSVFormatter(header bool) *delimitedFormatter {
+	return &delimitedFormatter{
+		header: header,
+		comma:  commaTSV,
+	}
+}
+
+func (f *delimitedFormatter) format(w writef, cols []string, rows [][]interface{}) {
+	if len(cols) == 0 {
+		return
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = f.comma
+	if f.header {
+		cw.Write(cols)
+	}
+	record := make([]string, len(cols))
+	for _, row := range rows {
+		for i, val := range row {
+			record[i] = f.cell(val)
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+}
+
+// formatStatus writes status as is: it is not a record.
+func (f *delimitedFormatter) formatStatus(w writef, status string) {
+	w("%s", status)
+	w.n()
+}
+
+// cell converts val to a string the same way tableFormatter does, except for nil values (NULLs), which become empty fields,
+// and bytes, which are hex-encoded with the \x prefix the way Postgres exports bytea values to CSV.
+func (f *delimitedFormatter) cell(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return bytesPrefix + hex.EncodeToString(v)
+	}
+	return fmt.Sprintf("%"+string(verbs[typeOf(val)]), val)
+}
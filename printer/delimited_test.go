@@ -0,0 +1,191 @@
+package printer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDelimitedFormatterCell(t *testing.T) {
+	tests := []struct {
+		val      interface{}
+		expected string
+	}{
+		{
+			val:      nil,
+			expected: "",
+		},
+		{
+			val:      "foo",
+			expected: "foo",
+		},
+		{
+			val:      "",
+			expected: "",
+		},
+		{
+			val:      12,
+			expected: "12",
+		},
+		{
+			val:      1.2300,
+			expected: "1.23",
+		},
+		{
+			val:      true,
+			expected: "true",
+		},
+		{
+			val:      []byte{1, 2, 3},
+			expected: `\x010203`,
+		},
+		{
+			val:      []byte{},
+			expected: `\x`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(fmt.Sprintf("%v", test.val), func(t *testing.T) {
+			t.Parallel()
+			f := &delimitedFormatter{}
+			res := f.cell(test.val)
+			if res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestDelimitedFormatterFormat(t *testing.T) {
+	cols := []string{"id", "name"}
+	rows := [][]interface{}{
+		{1, "one"},
+		{20, "twenty"},
+	}
+	tests := []struct {
+		desc     string
+		f        *delimitedFormatter
+		cols     []string
+		rows     [][]interface{}
+		expected string
+	}{
+		{
+			desc:     "empty cols",
+			f:        newCSVFormatter(true),
+			cols:     []string{},
+			rows:     rows,
+			expected: "",
+		},
+		{
+			desc:     "csv with header",
+			f:        newCSVFormatter(true),
+			cols:     cols,
+			rows:     rows,
+			expected: "id,name\n1,one\n20,twenty\n",
+		},
+		{
+			desc:     "csv w/out header",
+			f:        newCSVFormatter(false),
+			cols:     cols,
+			rows:     rows,
+			expected: "1,one\n20,twenty\n",
+		},
+		{
+			desc:     "csv, header only",
+			f:        newCSVFormatter(true),
+			cols:     cols,
+			rows:     [][]interface{}{},
+			expected: "id,name\n",
+		},
+		{
+			desc: "csv, quoting",
+			f:    newCSVFormatter(true),
+			cols: []string{"id", "a,b"},
+			rows: [][]interface{}{
+				{1, `"foo" (555-12-34), rate: $5.23`},
+				{2, "one, two"},
+				{3, "line\nbreak"},
+				{4, []byte{1, 2}},
+				{5, nil},
+			},
+			expected: "id,\"a,b\"\n1,\"\"\"foo\"\" (555-12-34), rate: $5.23\"\n2,\"one, two\"\n3,\"line\nbreak\"\n4,\\x0102\n5,\n",
+		},
+		{
+			desc:     "tsv with header",
+			f:        newTSVFormatter(true),
+			cols:     cols,
+			rows:     rows,
+			expected: "id\tname\n1\tone\n20\ttwenty\n",
+		},
+		{
+			desc:     "tsv w/out header",
+			f:        newTSVFormatter(false),
+			cols:     cols,
+			rows:     rows,
+			expected: "1\tone\n20\ttwenty\n",
+		},
+		{
+			desc: "tsv, quoting",
+			f:    newTSVFormatter(false),
+			cols: cols,
+			rows: [][]interface{}{
+				{1, "one\ttwo"},
+				{2, "one, two"},
+				{3, `"foo"`},
+			},
+			expected: "1\t\"one\ttwo\"\n2\tone, two\n3\t\"\"\"foo\"\"\"\n",
+		},
+		{
+			desc: "percent signs are not treated as format verbs",
+			f:    newCSVFormatter(false),
+			cols: cols,
+			rows: [][]interface{}{
+				{1, "100%d"},
+			},
+			expected: "1,100%d\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			w, checkWrote := makeTestWritef(t)
+			test.f.format(w, test.cols, test.rows)
+			checkWrote(test.expected)
+		})
+	}
+}
+
+func TestNewDelimitedFormatter(t *testing.T) {
+	tests := []struct {
+		desc          string
+		new           func(bool) *delimitedFormatter
+		header        bool
+		expectedComma rune
+	}{
+		{
+			desc:          "csv",
+			new:           newCSVFormatter,
+			header:        true,
+			expectedComma: commaCSV,
+		},
+		{
+			desc:          "tsv",
+			new:           newTSVFormatter,
+			expectedComma: commaTSV,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			f := test.new(test.header)
+			if f.header != test.header {
+				t.Fatalf("expected header to be %t but is was not", test.header)
+			}
+			if f.comma != test.expectedComma {
+				t.Fatalf("expected comma to be %q but it was %q", test.expectedComma, f.comma)
+			}
+		})
+	}
+}
@@ -2,20 +2,23 @@ package printer
 
 import "fmt"
 
-type format string
+// Format is an output format.
+type Format string
 
-func (f format) isValid() error {
+func (f Format) isValid() error {
 	switch f {
-	case FormatTable, FormatCSV, FormatTSV:
+	case FormatTable, FormatCSV, FormatTSV, FormatJSON, FormatNDJSON:
 		return nil
 	}
 	return fmt.Errorf("invalid format: %s", f)
 }
 
 const (
-	FormatTable format = "table"
-	FormatCSV          = "csv"
-	FormatTSV          = "tsv"
+	FormatTable  Format = "table"
+	FormatCSV           = "csv"
+	FormatTSV           = "tsv"
+	FormatJSON          = "json"
+	FormatNDJSON        = "ndjson"
 )
 
 type typ byte
@@ -40,10 +43,11 @@ var verbs = map[typ]byte{
 
 type formatter interface {
 	format(w writef, cols []string, rows [][]interface{})
+	formatStatus(w writef, status string)
 }
 
 type formatterBuilder struct {
-	format  format
+	format  Format
 	header  bool
 	spacing int
 }
@@ -53,16 +57,21 @@ func (b *formatterBuilder) build() (formatter, error) {
 	switch b.format {
 	case "", FormatTable:
 		res = newTableFormatter(b.header, b.spacing)
-	// TODO:
-	// case CSV:
-	// case TSV:
+	case FormatCSV:
+		res = newCSVFormatter(b.header)
+	case FormatTSV:
+		res = newTSVFormatter(b.header)
+	case FormatJSON:
+		res = newJSONFormatter()
+	case FormatNDJSON:
+		res = newNDJSONFormatter()
 	default:
 		return nil, fmt.Errorf("unknown format: %q", b.format)
 	}
 	return res, nil
 }
 
-func WithFormat(f format) func(*formatterBuilder) error {
+func WithFormat(f Format) func(*formatterBuilder) error {
 	return func(b *formatterBuilder) error {
 		if err := f.isValid(); err != nil {
 			return err
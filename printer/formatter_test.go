@@ -50,7 +50,7 @@ func TestTypeOf(t *testing.T) {
 
 func TestWithFormat(t *testing.T) {
 	tests := []struct {
-		format format
+		format Format
 		err    bool
 	}{
 		{
@@ -62,6 +62,12 @@ func TestWithFormat(t *testing.T) {
 		{
 			format: FormatTSV,
 		},
+		{
+			format: FormatJSON,
+		},
+		{
+			format: FormatNDJSON,
+		},
 		{
 			format: "foo",
 			err:    true,
@@ -151,8 +157,35 @@ func TestFormatterBuilderBuild(t *testing.T) {
 		}
 		return nil
 	}
+	checkDelimited := func(comma rune) func(formatter, bool) error {
+		return func(f formatter, header bool) error {
+			df, ok := f.(*delimitedFormatter)
+			if !ok {
+				return fmt.Errorf("expected %T but got %T", &delimitedFormatter{}, f)
+			}
+			if df.header != header {
+				return fmt.Errorf("expected header to be %t but it was not", header)
+			}
+			if df.comma != comma {
+				return fmt.Errorf("expected comma to be %q but it was %q", comma, df.comma)
+			}
+			return nil
+		}
+	}
+	checkJSON := func(lines bool) func(formatter, bool) error {
+		return func(f formatter, _ bool) error {
+			jf, ok := f.(*jsonFormatter)
+			if !ok {
+				return fmt.Errorf("expected %T but got %T", &jsonFormatter{}, f)
+			}
+			if jf.lines != lines {
+				return fmt.Errorf("expected lines to be %t but it was not", lines)
+			}
+			return nil
+		}
+	}
 	tests := []struct {
-		format format
+		format Format
 		header bool
 		check  func(formatter, bool) error
 		err    bool
@@ -167,6 +200,25 @@ func TestFormatterBuilderBuild(t *testing.T) {
 			header: false,
 			check:  checkTable,
 		},
+		{
+			format: FormatCSV,
+			header: true,
+			check:  checkDelimited(commaCSV),
+		},
+		{
+			format: FormatTSV,
+			header: false,
+			check:  checkDelimited(commaTSV),
+		},
+		{
+			format: FormatJSON,
+			header: true,
+			check:  checkJSON(false),
+		},
+		{
+			format: FormatNDJSON,
+			check:  checkJSON(true),
+		},
 		{
 			format: "foo",
 			err:    true,
@@ -192,7 +244,7 @@ func TestFormatterBuilderBuild(t *testing.T) {
 
 func TestFormatIsValid(t *testing.T) {
 	tests := []struct {
-		format format
+		format Format
 		err    bool
 	}{
 		{
@@ -204,6 +256,12 @@ func TestFormatIsValid(t *testing.T) {
 		{
 			format: FormatTSV,
 		},
+		{
+			format: FormatJSON,
+		},
+		{
+			format: FormatNDJSON,
+		},
 		{
 			format: "foo",
 			err:    true,
@@ -0,0 +1,78 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonFormatter writes rows as JSON objects keyed by column name, with keys following the column order.
+//
+// Values implementing json.Marshaler (e.g., decoded out-messages) are written as-is, which makes protobufs nested objects rather than strings.
+// If lines is true, one object per line (NDJSON) is written; otherwise, all objects are written as a single JSON array.
+type jsonFormatter struct {
+	lines bool
+}
+
+func newJSONFormatter() *jsonFormatter {
+	return &jsonFormatter{}
+}
+
+func newNDJSONFormatter() *jsonFormatter {
+	return &jsonFormatter{lines: true}
+}
+
+func (f *jsonFormatter) format(w writef, cols []string, rows [][]interface{}) {
+	if len(cols) == 0 {
+		return
+	}
+	keys := make([][]byte, 0, len(cols))
+	for _, col := range cols {
+		keys = append(keys, f.value(col))
+	}
+	if f.lines {
+		for _, row := range rows {
+			f.writeRow(w, keys, row)
+			w.n()
+		}
+		return
+	}
+	w("[")
+	for i, row := range rows {
+		if i > 0 {
+			w(",")
+		}
+		w.n()
+		f.writeRow(w, keys, row)
+	}
+	if len(rows) > 0 {
+		w.n()
+	}
+	w("]")
+	w.n()
+}
+
+// formatStatus writes status as a {"status": ...} object on its own line, for both JSON and NDJSON.
+func (f *jsonFormatter) formatStatus(w writef, status string) {
+	w(`{"status":%s}`, f.value(status))
+	w.n()
+}
+
+func (f *jsonFormatter) writeRow(w writef, keys [][]byte, row []interface{}) {
+	w("{")
+	for i, val := range row {
+		if i > 0 {
+			w(",")
+		}
+		w("%s:%s", keys[i], f.value(val))
+	}
+	w("}")
+}
+
+// value marshals val to JSON, falling back to its string representation for values JSON does not support (e.g., NaN).
+func (f *jsonFormatter) value(val interface{}) []byte {
+	res, err := json.Marshal(val)
+	if err != nil {
+		res, _ = json.Marshal(fmt.Sprint(val))
+	}
+	return res
+}
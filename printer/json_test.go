@@ -0,0 +1,157 @@
+package printer
+
+import (
+	"math"
+	"testing"
+)
+
+type testMessage struct{}
+
+func (m testMessage) String() string {
+	return "foo (1)"
+}
+
+func (m testMessage) MarshalJSON() ([]byte, error) {
+	return []byte(`{ "name": "foo",  "id": 1 }`), nil
+}
+
+func TestJSONFormatterValue(t *testing.T) {
+	tests := []struct {
+		desc     string
+		val      interface{}
+		expected string
+	}{
+		{
+			desc:     "nil",
+			val:      nil,
+			expected: "null",
+		},
+		{
+			desc:     "string",
+			val:      `foo "bar"`,
+			expected: `"foo \"bar\""`,
+		},
+		{
+			desc:     "int",
+			val:      12,
+			expected: "12",
+		},
+		{
+			desc:     "float",
+			val:      1.23,
+			expected: "1.23",
+		},
+		{
+			desc:     "bool",
+			val:      true,
+			expected: "true",
+		},
+		{
+			desc:     "bytes",
+			val:      []byte{1, 2, 3},
+			expected: `"AQID"`,
+		},
+		{
+			desc:     "json.Marshaler",
+			val:      testMessage{},
+			expected: `{"name":"foo","id":1}`,
+		},
+		{
+			desc:     "unsupported value",
+			val:      math.NaN(),
+			expected: `"NaN"`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			f := &jsonFormatter{}
+			res := string(f.value(test.val))
+			if res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestJSONFormatterFormat(t *testing.T) {
+	cols := []string{"id", "name", "msg"}
+	rows := [][]interface{}{
+		{1, "one", testMessage{}},
+		{20, "twenty", nil},
+	}
+	tests := []struct {
+		desc     string
+		f        *jsonFormatter
+		cols     []string
+		rows     [][]interface{}
+		expected string
+	}{
+		{
+			desc:     "json, empty cols",
+			f:        newJSONFormatter(),
+			cols:     []string{},
+			rows:     rows,
+			expected: "",
+		},
+		{
+			desc:     "json, no rows",
+			f:        newJSONFormatter(),
+			cols:     cols,
+			rows:     [][]interface{}{},
+			expected: "[]\n",
+		},
+		{
+			desc:     "json",
+			f:        newJSONFormatter(),
+			cols:     cols,
+			rows:     rows,
+			expected: "[\n{\"id\":1,\"name\":\"one\",\"msg\":{\"name\":\"foo\",\"id\":1}},\n{\"id\":20,\"name\":\"twenty\",\"msg\":null}\n]\n",
+		},
+		{
+			desc:     "json, column order is preserved",
+			f:        newJSONFormatter(),
+			cols:     []string{"b", "a"},
+			rows:     [][]interface{}{{1, 2}},
+			expected: "[\n{\"b\":1,\"a\":2}\n]\n",
+		},
+		{
+			desc:     "ndjson, empty cols",
+			f:        newNDJSONFormatter(),
+			cols:     []string{},
+			rows:     rows,
+			expected: "",
+		},
+		{
+			desc:     "ndjson, no rows",
+			f:        newNDJSONFormatter(),
+			cols:     cols,
+			rows:     [][]interface{}{},
+			expected: "",
+		},
+		{
+			desc:     "ndjson",
+			f:        newNDJSONFormatter(),
+			cols:     cols,
+			rows:     rows,
+			expected: "{\"id\":1,\"name\":\"one\",\"msg\":{\"name\":\"foo\",\"id\":1}}\n{\"id\":20,\"name\":\"twenty\",\"msg\":null}\n",
+		},
+		{
+			desc:     "percent signs are not treated as format verbs",
+			f:        newNDJSONFormatter(),
+			cols:     []string{"%s"},
+			rows:     [][]interface{}{{"100%d"}},
+			expected: "{\"%s\":\"100%d\"}\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			w, checkWrote := makeTestWritef(t)
+			test.f.format(w, test.cols, test.rows)
+			checkWrote(test.expected)
+		})
+	}
+}
@@ -16,6 +16,12 @@ func (w writef) repeat(s string, count int) {
 	w(strings.Repeat(s, count))
 }
 
+// Write makes writef an io.Writer.
+func (w writef) Write(p []byte) (int, error) {
+	w("%s", p)
+	return len(p), nil
+}
+
 type Printer struct {
 	w writef
 	f formatter
@@ -44,3 +50,8 @@ func New(w io.Writer, options ...func(*formatterBuilder) error) (*Printer, error
 func (p *Printer) Print(cols []string, rows [][]interface{}) {
 	p.f.format(p.w, cols, rows)
 }
+
+// PrintStatus prints the status of a statement that returns no rows, e.g., "UPDATE 3".
+func (p *Printer) PrintStatus(status string) {
+	p.f.formatStatus(p.w, status)
+}
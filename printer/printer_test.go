@@ -124,6 +124,21 @@ func TestPrinterPrint(t *testing.T) {
 			},
 			expected: expectedHeaderSpacing2 + expectedTableSpacing2,
 		},
+		{
+			desc: "csv + header",
+			options: []func(*formatterBuilder) error{
+				WithHeader(true),
+				WithFormat(FormatCSV),
+			},
+			expected: "id,name\n1,one\n20,twenty\n",
+		},
+		{
+			desc: "tsv",
+			options: []func(*formatterBuilder) error{
+				WithFormat(FormatTSV),
+			},
+			expected: "1\tone\n20\ttwenty\n",
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -151,6 +166,17 @@ func TestWritefN(t *testing.T) {
 	w.n()
 }
 
+func TestWritefWrite(t *testing.T) {
+	w, checkWrote := makeTestWritef(t)
+	const s = "100% %d"
+	n, err := w.Write([]byte(s))
+	testcheck.FatalIf(t, err)
+	if n != len(s) {
+		t.Fatalf("expected %d but got %d", len(s), n)
+	}
+	checkWrote(s)
+}
+
 func TestWritefRepeat(t *testing.T) {
 	tests := []struct {
 		s        string
@@ -186,3 +212,44 @@ func TestWritefRepeat(t *testing.T) {
 		})
 	}
 }
+
+func TestPrinterPrintStatus(t *testing.T) {
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{
+			format:   FormatTable,
+			expected: "UPDATE 3\n",
+		},
+		{
+			format:   FormatCSV,
+			expected: "UPDATE 3\n",
+		},
+		{
+			format:   FormatTSV,
+			expected: "UPDATE 3\n",
+		},
+		{
+			format:   FormatJSON,
+			expected: "{\"status\":\"UPDATE 3\"}\n",
+		},
+		{
+			format:   FormatNDJSON,
+			expected: "{\"status\":\"UPDATE 3\"}\n",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(string(test.format), func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			p, err := New(&buf, WithFormat(test.format))
+			testcheck.FatalIf(t, err)
+			p.PrintStatus("UPDATE 3")
+			if res := buf.String(); res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
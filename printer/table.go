@@ -28,6 +28,11 @@ func newTableFormatter(header bool, cellSpacing int) *tableFormatter {
 	}
 }
 
+func (f *tableFormatter) formatStatus(w writef, status string) {
+	w("%s", status)
+	w.n()
+}
+
 func (f *tableFormatter) format(w writef, cols []string, rows [][]interface{}) {
 	if len(cols) == 0 {
 		return
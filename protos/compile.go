@@ -0,0 +1,84 @@
+package protos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// compile is the in-process alternative to protoc: it compiles files and returns the same FileDescriptorSet bytes protoc would.
+//
+// Imports are searched for under the roots and, if not found there, resolved with the linked well-known types (see well-known.go).
+// Like protoc --include_imports, the set also contains the files' transitive imports, unless they have already been registered,
+// so that the files can be registered even if their imports were not selected.
+func (p *Protos) compile(files []*protoFile) ([]byte, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no input files")
+	}
+	roots := p.roots()
+	fss := make([]fs.FS, 0, len(roots))
+	for _, root := range roots {
+		fss = append(fss, p.makeFS(root))
+	}
+	c := protocompile.Compiler{
+		Resolver: protocompile.CompositeResolver{
+			&protocompile.SourceResolver{
+				Accessor: func(path string) (io.ReadCloser, error) {
+					for _, fsys := range fss {
+						f, err := fsys.Open(path)
+						if err == nil || !errors.Is(err, fs.ErrNotExist) {
+							return f, err
+						}
+					}
+					return nil, fs.ErrNotExist
+				},
+			},
+			protocompile.ResolverFunc(func(path string) (protocompile.SearchResult, error) {
+				fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+				if err != nil {
+					return protocompile.SearchResult{}, err
+				}
+				return protocompile.SearchResult{Desc: fd}, nil
+			}),
+		},
+	}
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	// TODO: context
+	compiled, err := c.Compile(context.Background(), paths...)
+	if err != nil {
+		return nil, withRoots(err, err.Error(), files)
+	}
+	fds := &descriptorpb.FileDescriptorSet{}
+	added := map[string]struct{}{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if _, ok := added[fd.Path()]; ok {
+			return
+		}
+		added[fd.Path()] = struct{}{}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			imp := imports.Get(i).FileDescriptor
+			if _, err := p.fileReg.FindFileByPath(imp.Path()); err == nil || imp.IsPlaceholder() {
+				continue
+			}
+			add(imp)
+		}
+	}
+	for _, fd := range compiled {
+		add(fd)
+	}
+	return proto.Marshal(fds)
+}
@@ -0,0 +1,201 @@
+package protos
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/cpb/internal/testproto"
+	"github.com/m18/eq"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestProtosCompile(t *testing.T) {
+	dir := filepath.Join("..", "internal", "testproto")
+	osFS := func(dir string) fs.FS { return os.DirFS(dir) }
+	rootsFS := func(dir string) fs.FS {
+		return map[string]fs.FS{
+			"a": fstest.MapFS{
+				"foo.proto": &fstest.MapFile{Data: []byte(`syntax = "proto3"; import "bar.proto"; message Foo { Bar bar = 1; }`)},
+				"baz.proto": &fstest.MapFile{Data: []byte(`syntax = "proto3"; message Baz {}`)},
+			},
+			"b": fstest.MapFS{
+				"bar.proto": &fstest.MapFile{Data: []byte(`syntax = "proto3"; message Bar {}`)},
+				"baz.proto": &fstest.MapFile{Data: []byte(`foobar`)}, // shadowed by a/baz.proto
+			},
+		}[dir]
+	}
+	tests := []struct {
+		desc        string
+		dir         string
+		importPaths []string
+		makeFS      func(string) fs.FS
+		fileReg     *protoregistry.Files
+		files       []string
+		expected    []string
+		err         bool
+	}{
+		{
+			desc:   "nil files",
+			dir:    dir,
+			makeFS: osFS,
+			files:  nil,
+			err:    true,
+		},
+		{
+			desc:   "empty files",
+			dir:    dir,
+			makeFS: osFS,
+			files:  []string{},
+			err:    true,
+		},
+		{
+			desc:     "well-known type imports",
+			dir:      dir,
+			makeFS:   osFS,
+			files:    []string{"foo.proto", "nested/bar.proto"},
+			expected: []string{"foo.proto", "google/protobuf/timestamp.proto", "nested/bar.proto"},
+		},
+		{
+			desc:     "well-known type imports, registered",
+			dir:      dir,
+			makeFS:   osFS,
+			fileReg:  protoregistry.GlobalFiles,
+			files:    []string{"foo.proto", "nested/bar.proto"},
+			expected: []string{"foo.proto", "nested/bar.proto"},
+		},
+		{
+			desc:     "imports under dir",
+			dir:      filepath.Join(dir, "lite"),
+			makeFS:   osFS,
+			files:    []string{"lists_lite.proto"},
+			expected: []string{"lists_lite.proto", "nested/bar_lite.proto"},
+		},
+		{
+			desc:   "invalid proto",
+			dir:    filepath.Join(dir, "invalid"),
+			makeFS: osFS,
+			files:  []string{"invalid.proto"},
+			err:    true,
+		},
+		{
+			desc:   "non-existent file",
+			dir:    dir,
+			makeFS: osFS,
+			files:  []string{"nonexistent.proto"},
+			err:    true,
+		},
+		{
+			desc:        "imports under another root",
+			dir:         "a",
+			importPaths: []string{"b"},
+			makeFS:      rootsFS,
+			files:       []string{"foo.proto", "baz.proto"},
+			expected:    []string{"foo.proto", "bar.proto", "baz.proto"},
+		},
+		{
+			desc:   "imports under another root, no import paths",
+			dir:    "a",
+			makeFS: rootsFS,
+			files:  []string{"foo.proto"},
+			err:    true,
+		},
+		{
+			desc: "non-existent import",
+			makeFS: func(string) fs.FS {
+				return fstest.MapFS{
+					"foo.proto": &fstest.MapFile{Data: []byte(`syntax = "proto3"; import "bar.proto";`)},
+				}
+			},
+			files: []string{"foo.proto"},
+			err:   true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			p := &Protos{
+				dir:         test.dir,
+				importPaths: test.importPaths,
+				makeFS:      test.makeFS,
+				fileReg:     test.fileReg,
+			}
+			var files []*protoFile
+			for _, path := range test.files {
+				files = append(files, &protoFile{root: test.dir, path: path})
+			}
+			res, err := p.compile(files)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			fds := &descriptorpb.FileDescriptorSet{}
+			testcheck.FatalIf(t, proto.Unmarshal(res, fds))
+			names := make([]string, 0, len(fds.GetFile()))
+			for _, fdp := range fds.GetFile() {
+				names = append(names, fdp.GetName())
+			}
+			if !eq.StringSlices(names, test.expected) {
+				t.Fatalf("expected %v but got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestProtosCompileMatchesProtoc(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	dir := filepath.Join("..", "internal", "testproto", "lite")
+	files := []*protoFile{
+		{root: dir, path: "foo_lite.proto"},
+		{root: dir, path: "nested/bar_lite.proto"},
+		{root: dir, path: "lists_lite.proto"},
+	}
+	fileDescriptorSet := func(protoc string) (*descriptorpb.FileDescriptorSet, error) {
+		p := &Protos{
+			protoc: protoc,
+			dir:    dir,
+			makeFS: func(dir string) fs.FS { return os.DirFS(dir) },
+			mute:   true,
+		}
+		b, err := p.fileDescriptorSetBytes(files)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", protoc, err)
+		}
+		res := &descriptorpb.FileDescriptorSet{}
+		return res, proto.Unmarshal(b, res)
+	}
+	expected, err := fileDescriptorSet(testproto.Protoc)
+	testcheck.FatalIf(t, err)
+	res, err := fileDescriptorSet(config.ProtocBuiltin)
+	testcheck.FatalIf(t, err)
+	byName := func(fds *descriptorpb.FileDescriptorSet) map[string]*descriptorpb.FileDescriptorProto {
+		res := map[string]*descriptorpb.FileDescriptorProto{}
+		for _, fdp := range fds.GetFile() {
+			res[fdp.GetName()] = fdp
+		}
+		return res
+	}
+	expectedByName, resByName := byName(expected), byName(res)
+	if len(resByName) != len(expectedByName) {
+		t.Fatalf("expected %d files but got %d", len(expectedByName), len(resByName))
+	}
+	for name, efdp := range expectedByName {
+		fdp, ok := resByName[name]
+		if !ok {
+			t.Fatalf("expected %q to be compiled but it was not", name)
+		}
+		if !proto.Equal(fdp, efdp) {
+			t.Fatalf("expected %q to compile to %v but got %v", name, efdp, fdp)
+		}
+	}
+}
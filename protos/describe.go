@@ -0,0 +1,67 @@
+package protos
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const describeIndent = "    "
+
+// Describe returns a .proto-like description of the specified message: its fields, their types and numbers, and its nested enums.
+func (p *Protos) Describe(message protoreflect.FullName) (string, error) {
+	md, err := p.messageDescriptor(message)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "message %s {\n", md.FullName())
+	fds := md.Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+		fmt.Fprintf(&sb, "%s%s %s = %d;", describeIndent, fieldType(fd), fd.Name(), fd.Number())
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			fmt.Fprintf(&sb, " // oneof %s", od.Name())
+		}
+		sb.WriteString("\n")
+	}
+	eds := md.Enums()
+	for i := 0; i < eds.Len(); i++ {
+		ed := eds.Get(i)
+		fmt.Fprintf(&sb, "\n%senum %s {\n", describeIndent, ed.Name())
+		vds := ed.Values()
+		for j := 0; j < vds.Len(); j++ {
+			vd := vds.Get(j)
+			fmt.Fprintf(&sb, "%s%s%s = %d;\n", describeIndent, describeIndent, vd.Name(), vd.Number())
+		}
+		fmt.Fprintf(&sb, "%s}\n", describeIndent)
+	}
+	sb.WriteString("}")
+	return sb.String(), nil
+}
+
+// fieldType returns the type of fd the way it is declared in .proto files, e.g., repeated example.Phone, map<string, int32>.
+func fieldType(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsMap():
+		return fmt.Sprintf("map<%s, %s>", kindType(fd.MapKey()), kindType(fd.MapValue()))
+	case fd.IsList():
+		return "repeated " + kindType(fd)
+	case fd.HasOptionalKeyword():
+		return "optional " + kindType(fd)
+	default:
+		return kindType(fd)
+	}
+}
+
+func kindType(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		return string(fd.Enum().FullName())
+	default:
+		return fd.Kind().String()
+	}
+}
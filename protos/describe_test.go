@@ -0,0 +1,92 @@
+package protos
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/m18/cpb/internal/testcheck"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestProtosDescribe(t *testing.T) {
+	p, err := makeTestProtosBuiltin(func(string) fs.FS {
+		return fstest.MapFS{
+			"foo.proto": &fstest.MapFile{Data: []byte(`
+				syntax = "proto3";
+				package foo;
+				message Foo {
+					string name = 1;
+					Bar bar = 2;
+					repeated Bar bars = 3;
+					map<string, int32> counts = 4;
+					optional Kind kind = 5;
+					oneof id {
+						int64 num = 6;
+						bytes uuid = 7;
+					}
+					enum Kind {
+						NONE = 0;
+						SOME = 1;
+					}
+				}
+				message Bar {}
+			`)},
+		}
+	})
+	testcheck.FatalIf(t, err)
+	tests := []struct {
+		desc     string
+		message  protoreflect.FullName
+		expected string
+		err      bool
+	}{
+		{
+			desc:    "fields and nested enums",
+			message: "foo.Foo",
+			expected: `message foo.Foo {
+    string name = 1;
+    foo.Bar bar = 2;
+    repeated foo.Bar bars = 3;
+    map<string, int32> counts = 4;
+    optional foo.Foo.Kind kind = 5;
+    int64 num = 6; // oneof id
+    bytes uuid = 7; // oneof id
+
+    enum Kind {
+        NONE = 0;
+        SOME = 1;
+    }
+}`,
+		},
+		{
+			desc:     "no fields",
+			message:  "foo.Bar",
+			expected: "message foo.Bar {\n}",
+		},
+		{
+			desc:    "non-existent message",
+			message: "foo.Baz",
+			err:     true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			res, err := p.Describe(test.message)
+			if err != nil {
+				if !test.err {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if test.err {
+				t.Fatalf("expected an error but didn't get one")
+			}
+			if res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
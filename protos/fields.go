@@ -0,0 +1,65 @@
+package protos
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Messages returns the full names of all registered messages, nested ones included, sorted.
+func (p *Protos) Messages() []protoreflect.FullName {
+	res := []protoreflect.FullName{}
+	var add func(mds protoreflect.MessageDescriptors)
+	add = func(mds protoreflect.MessageDescriptors) {
+		for i := 0; i < mds.Len(); i++ {
+			md := mds.Get(i)
+			if md.IsMapEntry() {
+				continue
+			}
+			res = append(res, md.FullName())
+			add(md.Messages())
+		}
+	}
+	p.fileReg.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		add(fd.Messages())
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// FieldPaths returns the dot-separated paths of the fields of the specified message, e.g., shard_id.shard, sorted.
+//
+// Paths go through message fields, repeated ones and map values included, but stop short of recursion.
+func (p *Protos) FieldPaths(message protoreflect.FullName) ([]string, error) {
+	md, err := p.messageDescriptor(message)
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	seen := map[protoreflect.FullName]struct{}{} // messages on the current path
+	var add func(md protoreflect.MessageDescriptor, prefix string)
+	add = func(md protoreflect.MessageDescriptor, prefix string) {
+		seen[md.FullName()] = struct{}{}
+		defer delete(seen, md.FullName())
+		fds := md.Fields()
+		for i := 0; i < fds.Len(); i++ {
+			fd := fds.Get(i)
+			path := prefix + string(fd.Name())
+			res = append(res, path)
+			child := fd.Message()
+			if fd.IsMap() {
+				child = fd.MapValue().Message()
+			}
+			if child == nil {
+				continue
+			}
+			if _, ok := seen[child.FullName()]; !ok {
+				add(child, path+".")
+			}
+		}
+	}
+	add(md, "")
+	sort.Strings(res)
+	return res, nil
+}
@@ -0,0 +1,92 @@
+package protos
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/eq"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestProtosMessages(t *testing.T) {
+	p, err := makeTestProtosBuiltin(func(string) fs.FS {
+		return fstest.MapFS{
+			"foo.proto": &fstest.MapFile{Data: []byte(`syntax = "proto3"; package foo; message Foo { map<string, Bar> bars = 1; message Bar {} } message Baz {}`)},
+		}
+	})
+	testcheck.FatalIf(t, err)
+	res := []string{}
+	for _, name := range p.Messages() {
+		res = append(res, string(name))
+	}
+	expected := []string{"foo.Baz", "foo.Foo", "foo.Foo.Bar"} // no map entries
+	if !eq.StringSlices(res, expected) {
+		t.Fatalf("expected %v but got %v", expected, res)
+	}
+}
+
+func TestProtosFieldPaths(t *testing.T) {
+	p, err := makeTestProtosBuiltin(func(string) fs.FS {
+		return fstest.MapFS{
+			"foo.proto": &fstest.MapFile{Data: []byte(`
+				syntax = "proto3";
+				package foo;
+				message Foo {
+					int32 id = 1;
+					Bar bar = 2;
+					repeated Bar bars = 3;
+					map<string, Bar> bars_by_name = 4;
+					map<string, string> labels = 5;
+				}
+				message Bar {
+					string name = 1;
+					Bar parent = 2;
+				}
+			`)},
+		}
+	})
+	testcheck.FatalIf(t, err)
+	tests := []struct {
+		desc     string
+		message  protoreflect.FullName
+		expected []string
+		err      bool
+	}{
+		{
+			desc:     "nested, repeated and map fields",
+			message:  "foo.Foo",
+			expected: []string{"bar", "bar.name", "bar.parent", "bars", "bars.name", "bars.parent", "bars_by_name", "bars_by_name.name", "bars_by_name.parent", "id", "labels"},
+		},
+		{
+			desc:     "recursive message",
+			message:  "foo.Bar",
+			expected: []string{"name", "parent"},
+		},
+		{
+			desc:    "non-existent message",
+			message: "foo.Baz",
+			err:     true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			res, err := p.FieldPaths(test.message)
+			if err != nil {
+				if !test.err {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if test.err {
+				t.Fatalf("expected an error but didn't get one")
+			}
+			if !eq.StringSlices(res, test.expected) {
+				t.Fatalf("expected %v but got %v", test.expected, res)
+			}
+		})
+	}
+}
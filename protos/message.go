@@ -0,0 +1,22 @@
+package protos
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// message is a decoded protobuf message.
+//
+// It is rendered with its out-message template when used as a fmt.Stringer, and as protojson when marshaled to JSON.
+type message struct {
+	s string
+	m proto.Message
+}
+
+func (m *message) String() string {
+	return m.s
+}
+
+func (m *message) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(m.m)
+}
@@ -0,0 +1,25 @@
+package protos
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/m18/cpb/internal/testcheck"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMessage(t *testing.T) {
+	m := &message{
+		s: "foo",
+		m: &timestamppb.Timestamp{Seconds: 1},
+	}
+	if s := m.String(); s != "foo" {
+		t.Fatalf("expected %q but got %q", "foo", s)
+	}
+	b, err := json.Marshal(map[string]interface{}{"ts": m})
+	testcheck.FatalIf(t, err)
+	const expected = `{"ts":"1970-01-01T00:00:01Z"}`
+	if string(b) != expected {
+		t.Fatalf("expected %s but got %s", expected, b)
+	}
+}
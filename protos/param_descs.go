@@ -1,57 +1,240 @@
 package protos
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/m18/cpb/config"
 	"github.com/m18/cpb/internal/tmpl"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
-type tplParamToFieldDescs map[string][]protoreflect.FieldDescriptor
+const fanOutSeparator = ", "
+
+type tplParamToFieldDescs map[string][]*fieldAccessor
+
+// fieldAccessor gets the value of a field, optionally indexing into it if it's a list or a map.
+type fieldAccessor struct {
+	fd  protoreflect.FieldDescriptor
+	all bool                 // fan out to all list items or map values
+	i   int                  // list index
+	key *protoreflect.MapKey // map key
+}
 
-// TODO: handle maps and lists
-//        in: (..., [123,456]), insert into samples(id, nam, dat) values(1, 'blah', $p(12, \"blah2\", 2010, \"uno\", [123,456]))
-//       out: $phones[0] $phones[*] (all?)
-//
-//       rm.Get(fd).List().Get(0).Message().Get(fd2)
-// func rangeRec(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
-// 	if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() {
-// 		v.Message().Range(rangeRec)
-// 	}
-// 	return true
-// }
 func newTplParamToFieldDescs(md protoreflect.MessageDescriptor, om *config.OutMessage) (tplParamToFieldDescs, error) {
 	res := tplParamToFieldDescs{}
 	for dotProps := range om.Props {
-		props := strings.Split(dotProps, ".")
-		fds := make([]protoreflect.FieldDescriptor, 0, len(props))
+		segments, err := tmpl.Segments(dotProps)
+		if err != nil {
+			return nil, err
+		}
+		fas := make([]*fieldAccessor, 0, len(segments))
 		currmd := md
-		for _, prop := range props {
+		for i, segment := range segments {
 			if currmd == nil {
 				return nil, fmt.Errorf("invalid property: %s", dotProps)
 			}
-			fd := currmd.Fields().ByName(protoreflect.Name(prop))
+			fd := currmd.Fields().ByName(protoreflect.Name(segment.Name))
 			if fd == nil {
-				return nil, fmt.Errorf("invalid property name: %s (%s)", prop, dotProps)
+				return nil, fmt.Errorf("invalid property name: %s (%s)", segment.Name, dotProps)
+			}
+			fa, err := newFieldAccessor(fd, segment)
+			if err != nil {
+				return nil, fmt.Errorf("%w (%s)", err, dotProps)
 			}
-			fds = append(fds, fd)
-			currmd = fd.Message()
+			if (fd.IsList() || fd.IsMap()) && !segment.IsIndexed() && i < len(segments)-1 {
+				return nil, fmt.Errorf("repeated field or map must be indexed: %s (%s)", segment.Name, dotProps)
+			}
+			fas = append(fas, fa)
+			if fd.IsMap() {
+				currmd = fd.MapValue().Message()
+			} else {
+				currmd = fd.Message()
+			}
+		}
+		res[tmpl.PropToTemplateParam(dotProps)] = fas
+	}
+	return res, nil
+}
+
+func newFieldAccessor(fd protoreflect.FieldDescriptor, segment tmpl.Segment) (*fieldAccessor, error) {
+	res := &fieldAccessor{fd: fd}
+	switch {
+	case !segment.IsIndexed():
+		// repeated fields and maps w/out an index are fanned out when they are the last accessor
+		res.all = fd.IsList() || fd.IsMap()
+	case !fd.IsList() && !fd.IsMap():
+		return nil, fmt.Errorf("cannot index a field that is neither repeated nor a map: %s", segment.Name)
+	case segment.Index == tmpl.IndexAll:
+		res.all = true
+	case fd.IsList():
+		i, err := strconv.Atoi(segment.Index)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repeated field index: %s[%s]", segment.Name, segment.Index)
+		}
+		res.i = i
+	default:
+		key, err := mapKey(fd.MapKey(), segment.Index)
+		if err != nil {
+			return nil, fmt.Errorf("invalid map key: %s[%s]: %w", segment.Name, segment.Index, err)
 		}
-		res[tmpl.PropToTemplateParam(dotProps)] = fds
+		res.key = &key
 	}
 	return res, nil
 }
 
+// mapKey converts a quoted string, a number, or a bool into the map key of kd's kind.
+func mapKey(kd protoreflect.FieldDescriptor, s string) (protoreflect.MapKey, error) {
+	var v protoreflect.Value
+	if kd.Kind() == protoreflect.StringKind {
+		str, err := strconv.Unquote(s)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("expected a quoted string")
+		}
+		v = protoreflect.ValueOfString(str)
+	} else {
+		var err error
+		if v, err = scalarMapKeyValue(kd.Kind(), s); err != nil {
+			return protoreflect.MapKey{}, err
+		}
+	}
+	return v.MapKey(), nil
+}
+
+func scalarMapKeyValue(kind protoreflect.Kind, s string) (protoreflect.Value, error) {
+	switch kind {
+	case protoreflect.BoolKind:
+		switch s {
+		case "true", "false":
+			return protoreflect.ValueOfBool(s == "true"), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("expected true or false")
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported key type: %s", kind)
+}
+
+// tplArgs returns template args for the values of rm's fields.
+//
+// Indexes that are out of range and map keys that are not present produce no args, i.e., "<no value>" in templates.
+// Fanned out values are joined by fanOutSeparator, with messages rendered as JSON.
 func (m tplParamToFieldDescs) tplArgs(rm protoreflect.Message) map[string]interface{} {
 	res := map[string]interface{}{}
-	for tplParam, fds := range m {
-		v := protoreflect.ValueOf(rm)
-		for _, fd := range fds {
-			v = v.Message().Get(fd)
+	for tplParam, fas := range m {
+		vs := []protoreflect.Value{protoreflect.ValueOf(rm)}
+		fannedOut := false
+		for _, fa := range fas {
+			var next []protoreflect.Value
+			for _, v := range vs {
+				next = append(next, fa.get(v.Message())...)
+			}
+			vs = next
+			fannedOut = fannedOut || fa.all
+		}
+		switch {
+		case fannedOut:
+			strs := make([]string, 0, len(vs))
+			for _, v := range vs {
+				strs = append(strs, fanOutString(v))
+			}
+			res[tplParam] = strings.Join(strs, fanOutSeparator)
+		case len(vs) == 1:
+			res[tplParam] = vs[0].Interface()
 		}
-		res[tplParam] = v.Interface()
 	}
 	return res
 }
+
+func fanOutString(v protoreflect.Value) string {
+	m, ok := v.Interface().(protoreflect.Message)
+	if !ok {
+		return fmt.Sprint(v.Interface())
+	}
+	b, err := protojson.Marshal(m.Interface())
+	if err != nil {
+		return fmt.Sprint(m.Interface())
+	}
+	// compacted for the same reason as in newDefaultStringer
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+// get returns the values of a's field in rm: one value, several values if a is fanning out, or none if there is nothing at the index.
+func (a *fieldAccessor) get(rm protoreflect.Message) []protoreflect.Value {
+	v := rm.Get(a.fd)
+	switch {
+	case a.fd.IsList():
+		l := v.List()
+		if a.all {
+			res := make([]protoreflect.Value, 0, l.Len())
+			for i := 0; i < l.Len(); i++ {
+				res = append(res, l.Get(i))
+			}
+			return res
+		}
+		if a.i >= l.Len() {
+			return nil
+		}
+		return []protoreflect.Value{l.Get(a.i)}
+	case a.fd.IsMap():
+		mp := v.Map()
+		if a.all {
+			return mapValues(mp)
+		}
+		if !mp.Has(*a.key) {
+			return nil
+		}
+		return []protoreflect.Value{mp.Get(*a.key)}
+	}
+	return []protoreflect.Value{v}
+}
+
+// mapValues returns the values of mp sorted by their keys.
+func mapValues(mp protoreflect.Map) []protoreflect.Value {
+	keys := make([]protoreflect.MapKey, 0, mp.Len())
+	mp.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(keys[i], keys[j])
+	})
+	res := make([]protoreflect.Value, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, mp.Get(k))
+	}
+	return res
+}
+
+func lessMapKey(a, b protoreflect.MapKey) bool {
+	switch av := a.Interface().(type) {
+	case string:
+		return av < b.String()
+	case bool:
+		return !av && b.Bool()
+	case int32, int64:
+		return a.Int() < b.Int()
+	case uint32, uint64:
+		return a.Uint() < b.Uint()
+	}
+	return false
+}
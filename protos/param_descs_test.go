@@ -246,3 +246,101 @@ func TestTplParamToFieldDescsTplArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestTplParamToFieldDescsNewListsAndMaps(t *testing.T) {
+	md, err := listsLiteMessageDescriptor()
+	testcheck.FatalIf(t, err)
+	tests := []struct {
+		prop string
+		err  bool
+	}{
+		{prop: "ids"},
+		{prop: "ids[0]"},
+		{prop: "ids[*]"},
+		{prop: "bars[1].nested.name"},
+		{prop: "bars[*].text"},
+		{prop: `labels["env"]`},
+		{prop: `labels[*]`},
+		{prop: `bars_by_id[5].text`},
+		{prop: `bars_by_id[*].nested.name`},
+		{prop: "flags[true]"},
+		{prop: "flags[*]"},
+		{prop: "bars.text", err: true},           // not indexed
+		{prop: "ids[0].foo", err: true},          // not a message
+		{prop: "ids[\"foo\"]", err: true},        // list index is not a number
+		{prop: "labels[1]", err: true},           // map key is not a string
+		{prop: "bars_by_id[\"1\"]", err: true},   // map key is not a number
+		{prop: "flags[1]", err: true},            // map key is not a bool
+		{prop: "bars[0].text[0]", err: true},     // neither repeated nor a map
+		{prop: "bars[0].nonexistent", err: true}, // unknown field
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.prop, func(t *testing.T) {
+			t.Parallel()
+			om := &config.OutMessage{Props: map[string]struct{}{test.prop: {}}}
+			res, err := newTplParamToFieldDescs(md, om)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if _, ok := res[tmpl.PropToTemplateParam(test.prop)]; !ok {
+				t.Fatalf("expected %q to be present but it was not", test.prop)
+			}
+		})
+	}
+}
+
+func TestTplParamToFieldDescsTplArgsListsAndMaps(t *testing.T) {
+	md, err := listsLiteMessageDescriptor()
+	testcheck.FatalIf(t, err)
+	const json = `{
+		"ids": [3, 1, 2],
+		"bars": [{"text": "one", "nested": {"name": "uno"}}, {"text": "two"}],
+		"labels": {"env": "prod", "app": "cpb"},
+		"bars_by_id": {"20": {"text": "twenty"}, "10": {"text": "ten"}},
+		"flags": {"true": "on", "false": "off"}
+	}`
+	tests := []struct {
+		prop     string
+		expected interface{} // nil if no value is expected
+	}{
+		{prop: "ids[0]", expected: int32(3)},
+		{prop: "ids[2]", expected: int32(2)},
+		{prop: "ids[3]"},
+		{prop: "ids[*]", expected: "3, 1, 2"},
+		{prop: "ids", expected: "3, 1, 2"},
+		{prop: "bars[0].nested.name", expected: "uno"},
+		{prop: "bars[1].nested.name", expected: ""},
+		{prop: "bars[5].nested.name"},
+		{prop: "bars[*].text", expected: "one, two"},
+		{prop: `labels["env"]`, expected: "prod"},
+		{prop: `labels["none"]`},
+		{prop: "labels[*]", expected: "cpb, prod"},
+		{prop: "bars_by_id[10].text", expected: "ten"},
+		{prop: "bars_by_id[*].text", expected: "ten, twenty"},
+		{prop: "flags[true]", expected: "on"},
+		{prop: "flags[*]", expected: "off, on"},
+		{prop: "bars[*]", expected: `{"text":"one","nested":{"name":"uno"}}, {"text":"two"}`},
+		{prop: "bars_by_id[*].nested", expected: `{}, {}`},
+	}
+	dm := dynamicpb.NewMessage(md)
+	testcheck.FatalIf(t, protojson.Unmarshal([]byte(json), dm))
+	for _, test := range tests {
+		test := test
+		t.Run(test.prop, func(t *testing.T) {
+			t.Parallel()
+			om := &config.OutMessage{Props: map[string]struct{}{test.prop: {}}}
+			tplParamToFieldDescs, err := newTplParamToFieldDescs(md, om)
+			testcheck.FatalIf(t, err)
+			args := tplParamToFieldDescs.tplArgs(dm)
+			res, ok := args[tmpl.PropToTemplateParam(test.prop)]
+			if ok != (test.expected != nil) {
+				t.Fatalf("expected value to be present: %t, but it was: %t", test.expected != nil, ok)
+			}
+			if res != test.expected {
+				t.Fatalf("expected %v (%T) but got %v (%T)", test.expected, test.expected, res, res)
+			}
+		})
+	}
+}
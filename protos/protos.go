@@ -7,9 +7,12 @@ import (
 	"io/fs"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/internal/glob"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
@@ -19,32 +22,43 @@ import (
 	"google.golang.org/protobuf/types/dynamicpb"
 )
 
-const protoExt = ".proto"
+const (
+	protoExt = ".proto"
+	jsonExt  = ".json"
+)
 
 // Protos performs protobuf-related operations.
 type Protos struct {
-	protoc        string
-	dir           string
-	deterministic bool
-	makeFS        func(string) fs.FS
-	fileReg       *protoregistry.Files
-	mute          bool
+	protoc         string
+	dir            string
+	importPaths    []string
+	include        []string
+	exclude        []string
+	descriptorSets []string
+	deterministic  bool
+	makeFS         func(string) fs.FS
+	fileReg        *protoregistry.Files
+	mute           bool
 }
 
-// New returns a new Protos performing operations with protobuf types under dir.
+// New returns a new Protos performing operations with protobuf types under cfg.Dir (and cfg.ImportPaths) and in cfg.DescriptorSets.
 //
-// dir can be an empty string, which implies that there is no intent to query protobufs.
-func New(protoc, dir string, deterministic bool, makeFS func(string) fs.FS, fileReg *protoregistry.Files, mute bool) (*Protos, error) {
+// Both can be empty, which implies that there is no intent to query protobufs.
+func New(cfg *config.Proto, makeFS func(string) fs.FS, fileReg *protoregistry.Files, mute bool) (*Protos, error) {
 	if fileReg == nil {
 		fileReg = protoregistry.GlobalFiles
 	}
 	res := &Protos{
-		protoc:        protoc,
-		dir:           dir,
-		deterministic: deterministic,
-		makeFS:        makeFS,
-		fileReg:       fileReg,
-		mute:          mute,
+		protoc:         cfg.C,
+		dir:            cfg.Dir,
+		importPaths:    cfg.ImportPaths,
+		include:        cfg.Include,
+		exclude:        cfg.Exclude,
+		descriptorSets: cfg.DescriptorSets,
+		deterministic:  cfg.Deterministic,
+		makeFS:         makeFS,
+		fileReg:        fileReg,
+		mute:           mute,
 	}
 	if err := res.registerFiles(); err != nil {
 		return nil, err
@@ -73,31 +87,44 @@ func (p *Protos) ProtoBytes(message protoreflect.FullName, fromJSON string) ([]b
 	return res, nil
 }
 
-// StringerFor returns a function to convert protobuf-encoded messages represented by om to string.
+// ProtoJSON converts protobuf bytes of the specified message into its compact protojson representation.
+func (p *Protos) ProtoJSON(message protoreflect.FullName, b []byte) (string, error) {
+	stringer, err := p.StringerFor(&config.OutMessage{Name: message, Format: config.OutMessageFormatJSON})
+	if err != nil {
+		return "", err
+	}
+	res, err := stringer(b)
+	if err != nil {
+		return "", err
+	}
+	return res.String(), nil
+}
+
+// StringerFor returns a function to decode protobuf-encoded messages represented by om.
 //
-// TODO: default stringer (when template is not deifined, traverses and includes all props+values)
-func (p *Protos) StringerFor(om *config.OutMessage) (func([]byte) (string, error), error) {
+// The decoded message is a fmt.Stringer rendering om.Template (or, if there is no template, the entire message in om.Format),
+// and a json.Marshaler producing the message's protojson representation.
+func (p *Protos) StringerFor(om *config.OutMessage) (func([]byte) (fmt.Stringer, error), error) {
 	md, err := p.messageDescriptor(om.Name)
 	if err != nil {
 		return nil, err
 	}
-	tplParamToFieldDescs, err := newTplParamToFieldDescs(md, om)
+	stringer, err := newStringer(md, om)
 	if err != nil {
 		return nil, err
 	}
 	mt := dynamicpb.NewMessageType(md)
-	res := func(b []byte) (string, error) {
+	res := func(b []byte) (fmt.Stringer, error) {
 		rm := mt.New()
 		m := rm.Interface()
 		if err := proto.Unmarshal(b, m); err != nil {
-			return "", err
+			return nil, err
 		}
-		var buf bytes.Buffer
-		tplArgs := tplParamToFieldDescs.tplArgs(rm)
-		if err := om.Template.Execute(&buf, tplArgs); err != nil {
-			return "", err
+		s, err := stringer(rm)
+		if err != nil {
+			return nil, err
 		}
-		return buf.String(), nil
+		return &message{s: s, m: m}, nil
 	}
 	return res, nil
 }
@@ -115,16 +142,21 @@ func (p *Protos) messageDescriptor(message protoreflect.FullName) (protoreflect.
 }
 
 func (p *Protos) registerFiles() error {
+	for _, path := range p.descriptorSets {
+		if err := p.registerDescriptorSetFile(path); err != nil {
+			return fmt.Errorf("could not register descriptor set %q: %w", path, err)
+		}
+	}
 	if p.dir == "" {
 		// dir was not provided -- no intent to query protobufs
 		return nil
 	}
 	files, err := p.files()
 	if err != nil {
-		return fmt.Errorf("could not read dir %q: %w", p.dir, err)
+		return err
 	}
 	if len(files) == 0 {
-		return fmt.Errorf("dir %q does not contain %s files", protoExt, p.dir)
+		return fmt.Errorf("%q does not contain %s files", p.roots(), protoExt)
 	}
 	fdsb, err := p.fileDescriptorSetBytes(files)
 	if err != nil {
@@ -133,59 +165,181 @@ func (p *Protos) registerFiles() error {
 	return p.registerFileDescriptorSet(fdsb)
 }
 
-func (p *Protos) files() ([]string, error) {
-	res := []string{}
-	fsys := p.makeFS(p.dir)
-	// TODO: context
-	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+// protoFile is a .proto file found under one of the roots.
+type protoFile struct {
+	root string
+	path string // relative to root, i.e., the path it is imported by
+}
+
+// roots returns the import roots, in the order they are searched.
+func (p *Protos) roots() []string {
+	return append([]string{p.dir}, p.importPaths...)
+}
+
+// files returns the files to compile: those under the roots that are selected by the include and exclude globs.
+//
+// Like with protoc, a file shadows the files with the same path under the roots that follow.
+func (p *Protos) files() ([]*protoFile, error) {
+	res := []*protoFile{}
+	seen := map[string]struct{}{}
+	for _, root := range p.roots() {
+		fsys := p.makeFS(root)
+		// TODO: context
+		err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || d.Name() == "" || !strings.HasSuffix(d.Name(), protoExt) {
+				return nil
+			}
+			if _, ok := seen[path]; ok {
+				return nil
+			}
+			seen[path] = struct{}{}
+			ok, err := p.selected(path)
+			if err != nil {
+				return err
+			}
+			if ok {
+				res = append(res, &protoFile{root: root, path: path})
+			}
+			return nil
+		})
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("could not read dir %q: %w", root, err)
 		}
-		if d.IsDir() || d.Name() == "" {
-			return nil
+	}
+	return res, nil
+}
+
+func (p *Protos) selected(path string) (bool, error) {
+	res := len(p.include) == 0
+	for _, pattern := range p.include {
+		ok, err := glob.Match(pattern, path)
+		if err != nil {
+			return false, err
 		}
-		if strings.HasSuffix(d.Name(), protoExt) {
-			res = append(res, path)
+		if ok {
+			res = true
+			break
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
-	return res, nil
+	if !res {
+		return false, nil
+	}
+	for _, pattern := range p.exclude {
+		ok, err := glob.Match(pattern, path)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+	return true, nil
 }
 
 // TODO: absctract for testing
-func (p *Protos) fileDescriptorSetBytes(files []string) ([]byte, error) {
-	args := append(
-		[]string{"-I", p.dir, "--descriptor_set_out", os.Stdout.Name()},
-		files...,
-	)
+func (p *Protos) fileDescriptorSetBytes(files []*protoFile) ([]byte, error) {
+	if p.protoc == config.ProtocBuiltin {
+		return p.compile(files)
+	}
+	args := []string{}
+	for _, root := range p.roots() {
+		args = append(args, "-I", root)
+	}
+	// imports are included so that files importing the ones that were not selected can still be registered
+	args = append(args, "--include_imports", "--descriptor_set_out", os.Stdout.Name())
+	for _, f := range files {
+		args = append(args, f.path)
+	}
 	buf := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
 	cmd := exec.Command(p.protoc, args...) // TODO: exec.CommandContext()
 	cmd.Stdout = buf
 	if p.mute {
-		cmd.Stderr = io.Discard
+		cmd.Stderr = stderr
 	} else {
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
 	}
 	if err := cmd.Run(); err != nil {
-		return nil, err
+		return nil, withRoots(err, stderr.String(), files)
 	}
 	return buf.Bytes(), nil
 }
 
+// withRoots annotates err with the roots of the files that msg mentions, so that it is clear where they came from.
+//
+// Paths are matched as a whole, e.g., `a.proto` in `a.proto:1:1: ...` or `"a.proto"`, but not in `data.proto:1:1: ...`.
+func withRoots(err error, msg string, files []*protoFile) error {
+	descs := []string{}
+	for _, f := range files {
+		if mentionsPath(msg, f.path) {
+			descs = append(descs, fmt.Sprintf("%s in %q", f.path, f.root))
+		}
+	}
+	if len(descs) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (%s)", err, strings.Join(descs, ", "))
+}
+
+func mentionsPath(msg, path string) bool {
+	rx := regexp.MustCompile(`(?m)(?:^|[\s"'])` + regexp.QuoteMeta(path) + `(?:[:"']|$)`)
+	return rx.MatchString(msg)
+}
+
+// registerDescriptorSetFile registers a FileDescriptorSet file, either binary or, if the file has a .json extension, JSON.
+func (p *Protos) registerDescriptorSetFile(path string) error {
+	fsys := p.makeFS(filepath.Dir(path))
+	b, err := fs.ReadFile(fsys, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(filepath.Ext(path), jsonExt) {
+		return p.registerFileDescriptorSet(b)
+	}
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := protojson.Unmarshal(b, fds); err != nil {
+		return err
+	}
+	return p.registerFileDescriptors(fds)
+}
+
 func (p *Protos) registerFileDescriptorSet(fdsb []byte) error {
 	fds := &descriptorpb.FileDescriptorSet{}
 	if err := proto.Unmarshal(fdsb, fds); err != nil {
 		return err
 	}
+	return p.registerFileDescriptors(fds)
+}
+
+// registerFileDescriptors registers the files in fds, dependencies first regardless of their order in fds.
+//
+// Files that have already been registered, e.g., well-known types or the imports included in several descriptor sets, are skipped.
+func (p *Protos) registerFileDescriptors(fds *descriptorpb.FileDescriptorSet) error {
+	fdps := make(map[string]*descriptorpb.FileDescriptorProto, len(fds.GetFile()))
 	for _, fdp := range fds.GetFile() {
+		fdps[fdp.GetName()] = fdp
+	}
+	var register func(fdp *descriptorpb.FileDescriptorProto) error
+	register = func(fdp *descriptorpb.FileDescriptorProto) error {
+		if _, err := p.fileReg.FindFileByPath(fdp.GetName()); err == nil {
+			return nil
+		}
+		delete(fdps, fdp.GetName()) // guards against import cycles
+		for _, dep := range fdp.GetDependency() {
+			if depfdp, ok := fdps[dep]; ok {
+				if err := register(depfdp); err != nil {
+					return err
+				}
+			}
+		}
 		fd, err := protodesc.NewFile(fdp, p.fileReg)
 		if err != nil {
 			return err
 		}
-		if err = p.fileReg.RegisterFile(fd); err != nil {
+		return p.fileReg.RegisterFile(fd)
+	}
+	for _, fdp := range fds.GetFile() {
+		if err := register(fdp); err != nil {
 			return err
 		}
 	}
@@ -1,10 +1,13 @@
 package protos
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"testing/fstest"
 	"text/template"
@@ -13,8 +16,11 @@ import (
 	"github.com/m18/cpb/internal/testcheck"
 	"github.com/m18/cpb/internal/testproto"
 	"github.com/m18/eq"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func TestProtosNew(t *testing.T) {
@@ -193,8 +199,9 @@ func TestProtosStringerFor(t *testing.T) {
 			// TODO: support optional props (e.g., versioning -- a prop exists only in a newer version of a message)
 			desc: "non-existent prop name",
 			om: &config.OutMessage{
-				Name:  validom.Name,
-				Props: map[string]struct{}{"nonexistent": {}},
+				Name:     validom.Name,
+				Template: validom.Template,
+				Props:    map[string]struct{}{"nonexistent": {}},
 			},
 			err: true,
 		},
@@ -204,6 +211,23 @@ func TestProtosStringerFor(t *testing.T) {
 			b:           []byte{1, 2, 3},
 			stringerErr: true,
 		},
+		{
+			desc: "no template, json",
+			om: &config.OutMessage{
+				Name:   validom.Name,
+				Format: config.OutMessageFormatJSON,
+			},
+			b:        validb,
+			expected: `{"id":5,"text":"world","nested":{"name":"cosmos"}}`,
+		},
+		{
+			desc: "no template, unknown format",
+			om: &config.OutMessage{
+				Name:   validom.Name,
+				Format: "foo",
+			},
+			err: true,
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -214,14 +238,17 @@ func TestProtosStringerFor(t *testing.T) {
 			if test.err {
 				return
 			}
-			str, err := stringer(test.b)
+			m, err := stringer(test.b)
 			testcheck.FatalIfUnexpected(t, err, test.stringerErr)
 			if test.stringerErr {
 				return
 			}
-			if str != test.expected {
+			if str := m.String(); str != test.expected {
 				t.Fatalf("expected %q but got %q", test.expected, str)
 			}
+			if _, ok := m.(json.Marshaler); !ok {
+				t.Fatalf("expected %T to implement json.Marshaler but it did not", m)
+			}
 		})
 	}
 }
@@ -363,66 +390,148 @@ func TestProtosFiles(t *testing.T) {
 	dir := &fstest.MapFile{Mode: fs.ModeDir}
 	file := &fstest.MapFile{}
 	tests := []struct {
-		desc     string
-		fsys     fs.FS
-		expected []string
-		err      bool
+		desc        string
+		fss         map[string]fs.FS
+		importPaths []string
+		include     []string
+		exclude     []string
+		expected    []string // root:path
+		err         bool
 	}{
 		{
 			desc: "empty fs",
-			fsys: fstest.MapFS{},
+			fss:  map[string]fs.FS{"root": fstest.MapFS{}},
 		},
 		{
 			desc: "empty dir",
-			fsys: fstest.MapFS{"foo": dir},
+			fss:  map[string]fs.FS{"root": fstest.MapFS{"foo": dir}},
 		},
 		{
 			desc:     "single file",
-			fsys:     fstest.MapFS{"foo/bar.proto": file},
-			expected: []string{"foo/bar.proto"},
+			fss:      map[string]fs.FS{"root": fstest.MapFS{"foo/bar.proto": file}},
+			expected: []string{"root:foo/bar.proto"},
 		},
 		{
 			desc:     "single file, nested dir",
-			fsys:     fstest.MapFS{"foo/bar/baz.proto": file},
-			expected: []string{"foo/bar/baz.proto"},
+			fss:      map[string]fs.FS{"root": fstest.MapFS{"foo/bar/baz.proto": file}},
+			expected: []string{"root:foo/bar/baz.proto"},
 		},
 		{
 			desc: "multiple files",
-			fsys: fstest.MapFS{
+			fss: map[string]fs.FS{"root": fstest.MapFS{
 				"foo/bar.proto": file,
 				"foo/baz.proto": file,
-			},
+			}},
 			expected: []string{
-				"foo/bar.proto",
-				"foo/baz.proto",
+				"root:foo/bar.proto",
+				"root:foo/baz.proto",
 			},
 		},
 		{
 			desc: "multiple files, nested dir",
-			fsys: fstest.MapFS{
+			fss: map[string]fs.FS{"root": fstest.MapFS{
 				"foo/bar.proto":     file,
 				"foo/baz/qux.proto": file,
+			}},
+			expected: []string{
+				"root:foo/bar.proto",
+				"root:foo/baz/qux.proto",
+			},
+		},
+		{
+			desc: "non-proto files",
+			fss: map[string]fs.FS{"root": fstest.MapFS{
+				"foo/bar.proto": file,
+				"foo/bar.txt":   file,
+			}},
+			expected: []string{"root:foo/bar.proto"},
+		},
+		{
+			desc: "multiple roots",
+			fss: map[string]fs.FS{
+				"root":  fstest.MapFS{"foo/bar.proto": file},
+				"third": fstest.MapFS{"baz/qux.proto": file},
+			},
+			importPaths: []string{"third"},
+			expected: []string{
+				"root:foo/bar.proto",
+				"third:baz/qux.proto",
+			},
+		},
+		{
+			desc: "multiple roots, shadowed file",
+			fss: map[string]fs.FS{
+				"root":  fstest.MapFS{"foo/bar.proto": file},
+				"third": fstest.MapFS{"foo/bar.proto": file},
 			},
+			importPaths: []string{"third"},
+			expected:    []string{"root:foo/bar.proto"},
+		},
+		{
+			desc: "multiple roots, shadowed excluded file",
+			fss: map[string]fs.FS{
+				"root":  fstest.MapFS{"foo/bar.proto": file},
+				"third": fstest.MapFS{"foo/bar.proto": file},
+			},
+			importPaths: []string{"third"},
+			exclude:     []string{"foo/*"},
+		},
+		{
+			desc: "include",
+			fss: map[string]fs.FS{
+				"root":  fstest.MapFS{"foo/bar.proto": file, "foo/baz/qux.proto": file},
+				"third": fstest.MapFS{"google/api/http.proto": file},
+			},
+			importPaths: []string{"third"},
+			include:     []string{"foo/**"},
 			expected: []string{
-				"foo/bar.proto",
-				"foo/baz/qux.proto",
+				"root:foo/bar.proto",
+				"root:foo/baz/qux.proto",
 			},
 		},
+		{
+			desc: "include and exclude",
+			fss: map[string]fs.FS{
+				"root":  fstest.MapFS{"foo/bar.proto": file, "foo/baz/qux.proto": file},
+				"third": fstest.MapFS{"google/api/http.proto": file},
+			},
+			importPaths: []string{"third"},
+			include:     []string{"foo/**", "google/**/*.proto"},
+			exclude:     []string{"**/baz/*"},
+			expected: []string{
+				"root:foo/bar.proto",
+				"third:google/api/http.proto",
+			},
+		},
+		{
+			desc:    "invalid glob",
+			fss:     map[string]fs.FS{"root": fstest.MapFS{"foo/bar.proto": file}},
+			include: []string{"["},
+			err:     true,
+		},
 	}
 	for _, test := range tests {
 		test := test
 		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
 			p := &Protos{
-				makeFS: func(string) fs.FS { return test.fsys },
+				dir:         "root",
+				importPaths: test.importPaths,
+				include:     test.include,
+				exclude:     test.exclude,
+				makeFS:      func(dir string) fs.FS { return test.fss[dir] },
 			}
 			res, err := p.files()
 			testcheck.FatalIfUnexpected(t, err, test.err)
 			if test.err {
 				return
 			}
-			if !eq.StringSlices(res, test.expected) {
-				t.Fatalf("expected %v but got %v", test.expected, res)
+			files := make([]string, 0, len(res))
+			for _, f := range res {
+				files = append(files, f.root+":"+f.path)
+			}
+			if !eq.StringSlices(files, test.expected) {
+				t.Fatalf("expected %v but got %v", test.expected, files)
 			}
 		})
 	}
@@ -433,32 +542,56 @@ func TestProtosFileDescriptorSetBytes(t *testing.T) {
 		t.Skip()
 	}
 	dir := filepath.Join("..", "internal", "testproto")
-	files := []string{
-		filepath.Join(dir, "foo.proto"),
-		filepath.Join(dir, "nested", "bar.proto"),
+	invalidDir := filepath.Join(dir, "invalid")
+	files := []*protoFile{
+		{root: dir, path: "foo.proto"},
+		{root: dir, path: "nested/bar.proto"},
 	}
 	tests := []struct {
-		desc  string
-		dir   string
-		files []string
-		err   bool
+		desc          string
+		protoc        string
+		importPaths   []string
+		files         []*protoFile
+		expectedInErr string
+		err           bool
 	}{
 		{
-			desc:  "nil files",
-			dir:   dir,
-			files: nil,
-			err:   true,
+			desc:   "nil files",
+			protoc: testproto.Protoc,
+			files:  nil,
+			err:    true,
+		},
+		{
+			desc:   "empty files",
+			protoc: testproto.Protoc,
+			files:  []*protoFile{},
+			err:    true,
 		},
 		{
-			desc:  "empty files",
-			dir:   dir,
-			files: []string{},
-			err:   true,
+			desc:   "valid input",
+			protoc: testproto.Protoc,
+			files:  files,
 		},
 		{
-			desc:  "valid input",
-			dir:   dir,
-			files: files,
+			desc:   "valid input, builtin",
+			protoc: config.ProtocBuiltin,
+			files:  files,
+		},
+		{
+			desc:          "invalid proto under an import path",
+			protoc:        testproto.Protoc,
+			importPaths:   []string{invalidDir},
+			files:         append([]*protoFile{{root: invalidDir, path: "invalid.proto"}}, files...),
+			expectedInErr: fmt.Sprintf("invalid.proto in %q", invalidDir),
+			err:           true,
+		},
+		{
+			desc:          "invalid proto under an import path, builtin",
+			protoc:        config.ProtocBuiltin,
+			importPaths:   []string{invalidDir},
+			files:         append([]*protoFile{{root: invalidDir, path: "invalid.proto"}}, files...),
+			expectedInErr: fmt.Sprintf("invalid.proto in %q", invalidDir),
+			err:           true,
 		},
 	}
 	for _, test := range tests {
@@ -466,12 +599,18 @@ func TestProtosFileDescriptorSetBytes(t *testing.T) {
 		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
 			p := &Protos{
-				protoc: testproto.Protoc,
-				dir:    test.dir,
+				protoc:      test.protoc,
+				dir:         dir,
+				importPaths: test.importPaths,
+				makeFS:      func(dir string) fs.FS { return os.DirFS(dir) },
+				mute:        true,
 			}
 			res, err := p.fileDescriptorSetBytes(test.files)
 			testcheck.FatalIfUnexpected(t, err, test.err)
 			if test.err {
+				if !strings.Contains(err.Error(), test.expectedInErr) {
+					t.Fatalf("expected error to contain %q but it was %q", test.expectedInErr, err)
+				}
 				return
 			}
 			if res == nil || len(res) == 0 {
@@ -481,14 +620,72 @@ func TestProtosFileDescriptorSetBytes(t *testing.T) {
 	}
 }
 
+func TestWithRoots(t *testing.T) {
+	errCompile := errors.New("compile error")
+	files := []*protoFile{
+		{root: "foo", path: "a.proto"},
+		{root: "bar", path: "data.proto"},
+		{root: "baz", path: "nested/b.proto"},
+	}
+	tests := []struct {
+		desc     string
+		msg      string
+		expected string
+	}{
+		{
+			desc:     "no mentions",
+			msg:      "something went wrong",
+			expected: "compile error",
+		},
+		{
+			desc:     "path at the start",
+			msg:      "data.proto:3:1: syntax error",
+			expected: `compile error (data.proto in "bar")`,
+		},
+		{
+			desc:     "path on another line",
+			msg:      "warning\nnested/b.proto:1:1: syntax error",
+			expected: `compile error (nested/b.proto in "baz")`,
+		},
+		{
+			desc:     "quoted path",
+			msg:      `c.proto:1:1: Import "a.proto" was not found or had errors.`,
+			expected: `compile error (a.proto in "foo")`,
+		},
+		{
+			desc:     "path ending another path",
+			msg:      "other/nested/b.proto:1:1: syntax error",
+			expected: "compile error",
+		},
+		{
+			desc:     "several paths",
+			msg:      "a.proto:1:1: foo\ndata.proto:2:1: bar",
+			expected: `compile error (a.proto in "foo", data.proto in "bar")`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			err := withRoots(errCompile, test.msg, files)
+			if !errors.Is(err, errCompile) {
+				t.Fatalf("expected error to wrap %v but it did not", errCompile)
+			}
+			if err.Error() != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, err)
+			}
+		})
+	}
+}
+
 func TestProtosRegisterFileDescriptorSet(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
 	}
 	dir := filepath.Join("..", "internal", "testproto", "lite")
-	files := []string{
-		filepath.Join(dir, "foo_lite.proto"),
-		filepath.Join(dir, "nested", "bar_lite.proto"),
+	files := []*protoFile{
+		{root: dir, path: "foo_lite.proto"},
+		{root: dir, path: "nested/bar_lite.proto"},
 	}
 	p := &Protos{
 		protoc: testproto.Protoc,
@@ -551,3 +748,274 @@ func TestProtosRegisterFileDescriptorSet(t *testing.T) {
 		})
 	}
 }
+
+func TestProtosRegisterFileDescriptorSetOrder(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	fds, err := testFileDescriptorSet()
+	testcheck.FatalIf(t, err)
+	tests := []struct {
+		desc string
+		fds  func() *descriptorpb.FileDescriptorSet
+		err  bool
+	}{
+		{
+			desc: "dependencies first",
+			fds:  func() *descriptorpb.FileDescriptorSet { return fds },
+		},
+		{
+			desc: "dependencies last",
+			fds: func() *descriptorpb.FileDescriptorSet {
+				res := &descriptorpb.FileDescriptorSet{}
+				for i := len(fds.File) - 1; i >= 0; i-- {
+					res.File = append(res.File, fds.File[i])
+				}
+				return res
+			},
+		},
+		{
+			desc: "duplicate files",
+			fds: func() *descriptorpb.FileDescriptorSet {
+				return &descriptorpb.FileDescriptorSet{File: append(fds.File, fds.File...)}
+			},
+		},
+		{
+			desc: "missing dependency",
+			fds: func() *descriptorpb.FileDescriptorSet {
+				res := &descriptorpb.FileDescriptorSet{}
+				for _, fdp := range fds.File {
+					if fdp.GetName() != filepath.Join("nested", "bar_lite.proto") {
+						res.File = append(res.File, fdp)
+					}
+				}
+				return res
+			},
+			err: true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			p := &Protos{
+				fileReg: &protoregistry.Files{},
+			}
+			err := p.registerFileDescriptors(test.fds())
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if p.fileReg.NumFiles() != len(fds.File) {
+				t.Fatalf("expected number of files to be %d but it was %d", len(fds.File), p.fileReg.NumFiles())
+			}
+		})
+	}
+}
+
+func TestProtosRegisterDescriptorSetFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	fds, err := testFileDescriptorSet()
+	testcheck.FatalIf(t, err)
+	fdsb, err := proto.Marshal(fds)
+	testcheck.FatalIf(t, err)
+	fdsjson, err := protojson.Marshal(fds)
+	testcheck.FatalIf(t, err)
+	fsys := fstest.MapFS{
+		"lite.pb":      {Data: fdsb},
+		"lite.json":    {Data: fdsjson},
+		"lite.bin":     {Data: fdsjson},
+		"garbage.json": {Data: []byte{1, 2, 3}},
+	}
+	tests := []struct {
+		desc string
+		path string
+		err  bool
+	}{
+		{
+			desc: "binary",
+			path: "lite.pb",
+		},
+		{
+			desc: "json",
+			path: "lite.json",
+		},
+		{
+			desc: "json, non-json extension",
+			path: "lite.bin",
+			err:  true,
+		},
+		{
+			desc: "garbage",
+			path: "garbage.json",
+			err:  true,
+		},
+		{
+			desc: "non-existent file",
+			path: "nonexistent.pb",
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			p := &Protos{
+				makeFS:  func(string) fs.FS { return fsys },
+				fileReg: &protoregistry.Files{},
+			}
+			err := p.registerDescriptorSetFile(test.path)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if _, err := p.messageDescriptor("testproto.lite.nested.Bar"); err != nil {
+				t.Fatalf("expected message to be registered but it was not: %v", err)
+			}
+		})
+	}
+}
+
+func TestProtosNewDescriptorSets(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	fds, err := testFileDescriptorSet()
+	testcheck.FatalIf(t, err)
+	fdsb, err := proto.Marshal(fds)
+	testcheck.FatalIf(t, err)
+	fsys := fstest.MapFS{"lite.pb": {Data: fdsb}}
+	tests := []struct {
+		desc string
+		cfg  *config.Proto
+		err  bool
+	}{
+		{
+			desc: "descriptor set only",
+			cfg:  &config.Proto{DescriptorSets: []string{"lite.pb"}},
+		},
+		{
+			desc: "same descriptor set twice",
+			cfg:  &config.Proto{DescriptorSets: []string{"lite.pb", "lite.pb"}},
+		},
+		{
+			desc: "non-existent descriptor set",
+			cfg:  &config.Proto{DescriptorSets: []string{"lite.pb", "nonexistent.pb"}},
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			p, err := New(test.cfg, func(string) fs.FS { return fsys }, &protoregistry.Files{}, true)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			if _, err := p.messageDescriptor("testproto.lite.Foo"); err != nil {
+				t.Fatalf("expected message to be registered but it was not: %v", err)
+			}
+		})
+	}
+}
+
+func TestProtosNewUnselectedImports(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	tests := []struct {
+		desc    string
+		protoc  string
+		include []string
+		exclude []string
+	}{
+		{
+			desc:    "include",
+			protoc:  testproto.Protoc,
+			include: []string{"lists_lite.proto"},
+		},
+		{
+			desc:    "include, builtin",
+			protoc:  config.ProtocBuiltin,
+			include: []string{"lists_lite.proto"},
+		},
+		{
+			desc:    "exclude, builtin",
+			protoc:  config.ProtocBuiltin,
+			exclude: []string{"nested/**"},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			cfg := &config.Proto{
+				C:       test.protoc,
+				Dir:     testproto.DirLite,
+				Include: test.include,
+				Exclude: test.exclude,
+			}
+			p, err := New(cfg, testproto.MakeFS, testproto.MakeFileReg(), testproto.Mute)
+			testcheck.FatalIf(t, err)
+			for _, message := range []protoreflect.FullName{"testproto.lite.Lists", "testproto.lite.nested.Bar"} {
+				if _, err := p.messageDescriptor(message); err != nil {
+					t.Fatalf("expected %s to be registered but it was not: %v", message, err)
+				}
+			}
+		})
+	}
+}
+
+func TestProtosProtoJSON(t *testing.T) {
+	p, err := makeTestProtosBuiltin(func(string) fs.FS {
+		return fstest.MapFS{
+			"foo.proto": &fstest.MapFile{Data: []byte(`syntax = "proto3"; package foo; message Foo { int64 id = 1; string text = 2; }`)},
+		}
+	})
+	testcheck.FatalIf(t, err)
+	tests := []struct {
+		desc     string
+		message  protoreflect.FullName
+		b        []byte
+		expected string
+		err      bool
+	}{
+		{
+			desc:     "message",
+			message:  "foo.Foo",
+			b:        []byte{0x08, 0x0a, 0x12, 0x03, 'b', 'a', 'r'},
+			expected: `{"id":"10","text":"bar"}`,
+		},
+		{
+			desc:     "empty message",
+			message:  "foo.Foo",
+			b:        []byte{},
+			expected: `{}`,
+		},
+		{
+			desc:    "invalid bytes",
+			message: "foo.Foo",
+			b:       []byte{0xff},
+			err:     true,
+		},
+		{
+			desc:    "non-existent message",
+			message: "foo.Bar",
+			err:     true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			res, err := p.ProtoJSON(test.message, test.b)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
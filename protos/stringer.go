@@ -0,0 +1,88 @@
+package protos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/m18/cpb/config"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// newStringer returns a function converting messages described by md to string.
+//
+// The function renders om.Template if it is defined, or the entire message in om.Format otherwise.
+func newStringer(md protoreflect.MessageDescriptor, om *config.OutMessage) (func(protoreflect.Message) (string, error), error) {
+	if om.Template == nil {
+		return newDefaultStringer(om.Format)
+	}
+	return newTemplateStringer(md, om)
+}
+
+func newTemplateStringer(md protoreflect.MessageDescriptor, om *config.OutMessage) (func(protoreflect.Message) (string, error), error) {
+	tplParamToFieldDescs, err := newTplParamToFieldDescs(md, om)
+	if err != nil {
+		return nil, err
+	}
+	res := func(rm protoreflect.Message) (string, error) {
+		var buf bytes.Buffer
+		tplArgs := tplParamToFieldDescs.tplArgs(rm)
+		if err := om.Template.Execute(&buf, tplArgs); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return res, nil
+}
+
+func newDefaultStringer(format string) (func(protoreflect.Message) (string, error), error) {
+	switch format {
+	case config.OutMessageFormatJSON:
+		return func(rm protoreflect.Message) (string, error) {
+			b, err := protojson.Marshal(rm.Interface())
+			if err != nil {
+				return "", err
+			}
+			// protojson output is unstable on purpose, e.g., it randomly adds spaces,
+			// compacting it makes the output the same across runs
+			var buf bytes.Buffer
+			if err = json.Compact(&buf, b); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		}, nil
+	case config.OutMessageFormatText:
+		return func(rm protoreflect.Message) (string, error) {
+			b, err := prototext.Marshal(rm.Interface())
+			if err != nil {
+				return "", err
+			}
+			// prototext output is unstable on purpose too, e.g., it randomly adds a space between fields
+			return compactText(string(b)), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown message format: %q", format)
+}
+
+// compactText collapses runs of spaces outside of the quoted strings of single-line prototext output.
+func compactText(s string) string {
+	var sb strings.Builder
+	quoted, escaped := false, false
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == ' ' && i > 0 && s[i-1] == ' ':
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
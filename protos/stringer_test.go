@@ -0,0 +1,146 @@
+package protos
+
+import (
+	"testing"
+
+	"github.com/m18/cpb/config"
+	"github.com/m18/cpb/internal/testcheck"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestNewDefaultStringer(t *testing.T) {
+	m := wrapperspb.String("foo")
+	fdp := &descriptorpb.FieldDescriptorProto{Name: proto.String(`a  "b"`), Number: proto.Int32(1), JsonName: proto.String("c")}
+	tests := []struct {
+		desc     string
+		format   string
+		m        proto.Message
+		expected string
+		err      bool
+	}{
+		{
+			desc:     "json",
+			format:   config.OutMessageFormatJSON,
+			m:        m,
+			expected: `"foo"`, // well-known wrapper types have special JSON representation
+		},
+		{
+			desc:     "json, several fields",
+			format:   config.OutMessageFormatJSON,
+			m:        fdp,
+			expected: `{"name":"a  \"b\"","number":1,"jsonName":"c"}`,
+		},
+		{
+			desc:     "text",
+			format:   config.OutMessageFormatText,
+			m:        m,
+			expected: `value:"foo"`,
+		},
+		{
+			desc:     "text, several fields",
+			format:   config.OutMessageFormatText,
+			m:        fdp,
+			expected: `name:"a  \"b\"" number:1 json_name:"c"`,
+		},
+		{
+			desc:   "empty format", // defaulted by config parsing
+			format: "",
+			err:    true,
+		},
+		{
+			desc:   "unknown format",
+			format: "foo",
+			err:    true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			stringer, err := newDefaultStringer(test.format)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			res, err := stringer(test.m.ProtoReflect())
+			testcheck.FatalIf(t, err)
+			if res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestCompactText(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{s: "", expected: ""},
+		{s: `a:1  b:2`, expected: `a:1 b:2`},
+		{s: `a:"x  y"  b:2`, expected: `a:"x  y" b:2`},
+		{s: `a:"x\"  y"  b:"\\"  c:3`, expected: `a:"x\"  y" b:"\\" c:3`},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.s, func(t *testing.T) {
+			t.Parallel()
+			if res := compactText(test.s); res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestNewStringer(t *testing.T) {
+	m := wrapperspb.String("foo")
+	md := m.ProtoReflect().Descriptor()
+	tests := []struct {
+		desc     string
+		om       *config.OutMessage
+		expected string
+		err      bool
+	}{
+		{
+			desc: "no template",
+			om: &config.OutMessage{
+				Format: config.OutMessageFormatJSON,
+			},
+			expected: `"foo"`,
+		},
+		{
+			desc: "template",
+			om: &config.OutMessage{
+				Template: testTemplate("v: {{.value}}"),
+				Props:    map[string]struct{}{"value": {}},
+			},
+			expected: "v: foo",
+		},
+		{
+			desc: "template, invalid prop",
+			om: &config.OutMessage{
+				Template: testTemplate("v: {{.foo}}"),
+				Props:    map[string]struct{}{"foo": {}},
+			},
+			err: true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			stringer, err := newStringer(md, test.om)
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if test.err {
+				return
+			}
+			res, err := stringer(m.ProtoReflect())
+			testcheck.FatalIf(t, err)
+			if res != test.expected {
+				t.Fatalf("expected %q but got %q", test.expected, res)
+			}
+		})
+	}
+}
@@ -2,9 +2,14 @@ package protos
 
 import (
 	"fmt"
+	"io/fs"
+	"text/template"
 
+	"github.com/m18/cpb/config"
 	"github.com/m18/cpb/internal/testproto"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func makeTestProtosLite() (*Protos, error) {
@@ -13,21 +18,40 @@ func makeTestProtosLite() (*Protos, error) {
 
 func makeTestProtos(dir string) (*Protos, error) {
 	return New(
-		testproto.Protoc,
-		dir,
-		testproto.Deterministic,
+		&config.Proto{
+			C:             testproto.Protoc,
+			Dir:           dir,
+			Deterministic: testproto.Deterministic,
+		},
 		testproto.MakeFS,
 		testproto.MakeFileReg(),
 		testproto.Mute,
 	)
 }
 
+// makeTestProtosBuiltin compiles the files in the FS made by makeFS in-process.
+func makeTestProtosBuiltin(makeFS func(string) fs.FS) (*Protos, error) {
+	return New(
+		&config.Proto{C: config.ProtocBuiltin, Dir: "."},
+		makeFS,
+		testproto.MakeFileReg(),
+		testproto.Mute,
+	)
+}
+
 func barLiteMessageDescriptor() (protoreflect.MessageDescriptor, error) {
+	return liteMessageDescriptor("testproto.lite.nested.Bar")
+}
+
+func listsLiteMessageDescriptor() (protoreflect.MessageDescriptor, error) {
+	return liteMessageDescriptor("testproto.lite.Lists")
+}
+
+func liteMessageDescriptor(name protoreflect.FullName) (protoreflect.MessageDescriptor, error) {
 	p, err := makeTestProtosLite()
 	if err != nil {
 		return nil, fmt.Errorf("error calling makeTestProtosLite: %w", err)
 	}
-	const name = "testproto.lite.nested.Bar"
 	d, err := p.fileReg.FindDescriptorByName(name)
 	if err != nil {
 		return nil, fmt.Errorf("could not find message %q: %w", name, err)
@@ -38,3 +62,30 @@ func barLiteMessageDescriptor() (protoreflect.MessageDescriptor, error) {
 	}
 	return md, nil
 }
+
+func testTemplate(s string) *template.Template {
+	return template.Must(template.New("").Parse(s))
+}
+
+// testFileDescriptorSet compiles the lite test protos with protoc.
+func testFileDescriptorSet() (*descriptorpb.FileDescriptorSet, error) {
+	files := []*protoFile{
+		{root: testproto.DirLite, path: "foo_lite.proto"},
+		{root: testproto.DirLite, path: "nested/bar_lite.proto"},
+		{root: testproto.DirLite, path: "lists_lite.proto"}, // imports bar_lite.proto
+	}
+	p := &Protos{
+		protoc: testproto.Protoc,
+		dir:    testproto.DirLite,
+		mute:   testproto.Mute,
+	}
+	fdsb, err := p.fileDescriptorSetBytes(files)
+	if err != nil {
+		return nil, fmt.Errorf("could not create file descriptor set: %w", err)
+	}
+	res := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(fdsb, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,123 @@
+package repl
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// inArgsrx matches an in-message alias with an opening parenthesis and no arguments yet, e.g., `$e(`.
+var inArgsrx = regexp.MustCompile(`\$(\w+)\(\s*$`)
+
+// Completer completes aliases, table and column names, message names, and field paths.
+type Completer struct {
+	InAliases  map[string][]string           // in-message alias parameter names, in order, keyed by alias
+	OutAliases []string                      // out-message aliases
+	Tables     map[string][]string           // column names keyed by table name
+	Messages   []string                      // message full names
+	FieldPaths func(message string) []string // dot-separated paths of the fields of message
+}
+
+// Complete returns the completions of the word in line ending at pos (in runes).
+// It is a liner.WordCompleter: head and tail are the parts of line that are kept around the completed word.
+//
+// `$` words complete to in-message aliases, e.g., `$e(`, and out-message aliases, e.g., `$e:`;
+// `$e(` completes to the alias's parameters, e.g., `$e(name, hireDate)`; `$e:` words complete to column names.
+// Words containing `.` complete to `table.column`, message names, and `message.field.path`.
+// Other words complete to table and column names.
+func (c *Completer) Complete(line string, pos int) (head string, completions []string, tail string) {
+	runes := []rune(line)
+	before, after := string(runes[:pos]), string(runes[pos:])
+	if m := inArgsrx.FindStringSubmatchIndex(before); m != nil {
+		alias := before[m[2]:m[3]]
+		if params, ok := c.InAliases[alias]; ok {
+			// replace the closing parenthesis, if any, too
+			return before[:m[0]], []string{"$" + alias + "(" + strings.Join(params, ", ") + ")"}, strings.TrimPrefix(after, ")")
+		}
+		return before, nil, after
+	}
+	runes = []rune(before)
+	start := len(runes)
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	return string(runes[:start]), c.complete(string(runes[start:])), after
+}
+
+func (c *Completer) complete(word string) []string {
+	res := []string{}
+	add := func(candidate string) {
+		if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(word)) {
+			res = append(res, candidate)
+		}
+	}
+	switch {
+	case word == "":
+		return nil
+	case strings.HasPrefix(word, "$"):
+		if i := strings.Index(word, ":"); i >= 0 {
+			for _, col := range c.columns() {
+				add(word[:i+1] + col)
+			}
+			break
+		}
+		for alias := range c.InAliases {
+			add("$" + alias + "(")
+		}
+		for _, alias := range c.OutAliases {
+			add("$" + alias + ":")
+		}
+	case strings.Contains(word, "."):
+		for table, cols := range c.Tables {
+			for _, col := range cols {
+				add(table + "." + col)
+			}
+		}
+		for _, message := range c.Messages {
+			add(message)
+			if c.FieldPaths != nil && strings.HasPrefix(word, message+".") {
+				for _, path := range c.FieldPaths(message) {
+					add(message + "." + path)
+				}
+			}
+		}
+	default:
+		for table := range c.Tables {
+			add(table)
+		}
+		for _, col := range c.columns() {
+			add(col)
+		}
+	}
+	sort.Strings(res)
+	return dedup(res)
+}
+
+// columns returns the column names of all tables.
+func (c *Completer) columns() []string {
+	res := []string{}
+	for _, cols := range c.Tables {
+		res = append(res, cols...)
+	}
+	sort.Strings(res)
+	return dedup(res)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || r == ':' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// dedup removes adjacent duplicates from sorted ss.
+func dedup(ss []string) []string {
+	if len(ss) == 0 {
+		return ss
+	}
+	res := ss[:1]
+	for _, s := range ss[1:] {
+		if s != res[len(res)-1] {
+			res = append(res, s)
+		}
+	}
+	return res
+}
@@ -0,0 +1,147 @@
+package repl
+
+import (
+	"testing"
+
+	"github.com/m18/eq"
+)
+
+func TestCompleterComplete(t *testing.T) {
+	c := &Completer{
+		InAliases: map[string][]string{
+			"e":   {"name", "hireDate", "isContractor"},
+			"emp": {},
+		},
+		OutAliases: []string{"e", "person_id"},
+		Tables: map[string][]string{
+			"employees": {"id", "details"},
+			"people":    {"id", "person_id", "name"},
+		},
+		Messages: []string{"example.Employee", "example.ID", "example.ID.ShardID"},
+		FieldPaths: func(message string) []string {
+			return map[string][]string{
+				"example.ID":         {"shard_id", "shard_id.id", "shard_id.shard", "uuid"},
+				"example.ID.ShardID": {"id", "shard"},
+			}[message]
+		},
+	}
+	tests := []struct {
+		desc                string
+		line                string
+		pos                 int
+		expectedHead        string
+		expectedCompletions []string
+		expectedTail        string
+	}{
+		{
+			desc:                "empty line",
+			line:                "",
+			expectedCompletions: nil,
+		},
+		{
+			desc:                "aliases",
+			line:                "select * from people where person_id = $",
+			pos:                 40,
+			expectedHead:        "select * from people where person_id = ",
+			expectedCompletions: []string{"$e(", "$e:", "$emp(", "$person_id:"},
+		},
+		{
+			desc:                "aliases with prefix",
+			line:                "select $p from people",
+			pos:                 9,
+			expectedHead:        "select ",
+			expectedCompletions: []string{"$person_id:"},
+			expectedTail:        " from people",
+		},
+		{
+			desc:                "in-message alias params",
+			line:                "insert into employees values ($e(",
+			pos:                 33,
+			expectedHead:        "insert into employees values (",
+			expectedCompletions: []string{"$e(name, hireDate, isContractor)"},
+		},
+		{
+			desc:                "in-message alias params, closing parenthesis",
+			line:                "values ($e( ));",
+			pos:                 12,
+			expectedHead:        "values (",
+			expectedCompletions: []string{"$e(name, hireDate, isContractor)"},
+			expectedTail:        ");",
+		},
+		{
+			desc:                "in-message alias params, no params",
+			line:                "$emp(",
+			pos:                 5,
+			expectedCompletions: []string{"$emp()"},
+		},
+		{
+			desc:         "unknown in-message alias params",
+			line:         "$foo(",
+			pos:          5,
+			expectedHead: "$foo(",
+		},
+		{
+			desc:                "out-message alias columns",
+			line:                "select $e:de",
+			pos:                 12,
+			expectedHead:        "select ",
+			expectedCompletions: []string{"$e:details"},
+		},
+		{
+			desc:                "tables and columns",
+			line:                "select * from PE",
+			pos:                 16,
+			expectedHead:        "select * from ",
+			expectedCompletions: []string{"people", "person_id"},
+		},
+		{
+			desc:                "distinct columns",
+			line:                "select i",
+			pos:                 8,
+			expectedHead:        "select ",
+			expectedCompletions: []string{"id"},
+		},
+		{
+			desc:                "qualified columns",
+			line:                "select people.",
+			pos:                 14,
+			expectedHead:        "select ",
+			expectedCompletions: []string{"people.id", "people.name", "people.person_id"},
+		},
+		{
+			desc:                "messages",
+			line:                "example.I",
+			pos:                 9,
+			expectedCompletions: []string{"example.ID", "example.ID.ShardID"},
+		},
+		{
+			desc:                "field paths",
+			line:                "example.ID.sh",
+			pos:                 13,
+			expectedCompletions: []string{"example.ID.ShardID", "example.ID.shard_id", "example.ID.shard_id.id", "example.ID.shard_id.shard"},
+		},
+		{
+			desc:                "non-ASCII",
+			line:                "select 'é' from pe",
+			pos:                 18,
+			expectedHead:        "select 'é' from ",
+			expectedCompletions: []string{"people", "person_id"},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			head, completions, tail := c.Complete(test.line, test.pos)
+			if head != test.expectedHead {
+				t.Fatalf("expected head to be %q but it was %q", test.expectedHead, head)
+			}
+			if !eq.StringSlices(completions, test.expectedCompletions) {
+				t.Fatalf("expected completions to be %v but they were %v", test.expectedCompletions, completions)
+			}
+			if tail != test.expectedTail {
+				t.Fatalf("expected tail to be %q but it was %q", test.expectedTail, tail)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/m18/cpb/script"
+	"github.com/peterh/liner"
+)
+
+const (
+	prompt         = "cpb> "
+	continuePrompt = "...> "
+	commandPrefix  = `\`
+
+	historyDirName  = "cpb"
+	historyFileName = "history"
+)
+
+var quitCommands = map[string]struct{}{
+	`\q`:   {},
+	"quit": {},
+	"exit": {},
+}
+
+// lineReader reads lines interactively, e.g., *liner.State.
+type lineReader interface {
+	Prompt(string) (string, error)
+	AppendHistory(string)
+}
+
+// REPL reads statements, possibly spanning several lines, interactively and passes them on for execution.
+type REPL struct {
+	r           lineReader
+	errw        io.Writer
+	historyPath string
+	close       func() error
+}
+
+// New returns a new REPL which keeps its history at historyPath, unless it is empty, and writes errors to errw.
+//
+// Tab completes words with c, unless it is nil.
+func New(historyPath string, errw io.Writer, c *Completer) (*REPL, error) {
+	l := liner.NewLiner()
+	l.SetCtrlCAborts(true)
+	l.SetMultiLineMode(true)
+	if c != nil {
+		l.SetWordCompleter(c.Complete)
+	}
+	res := &REPL{
+		r:           l,
+		errw:        errw,
+		historyPath: historyPath,
+	}
+	if err := res.readHistory(l); err != nil {
+		l.Close()
+		return nil, err
+	}
+	res.close = func() error {
+		defer l.Close()
+		return res.writeHistory(l)
+	}
+	return res, nil
+}
+
+// TerminalSupported reports whether the terminal supports line editing, e.g., reading a password without echoing it.
+func TerminalSupported() bool {
+	return liner.TerminalSupported()
+}
+
+// PromptPassword reads a password from the terminal without echoing it.
+func PromptPassword(p string) (string, error) {
+	l := liner.NewLiner()
+	defer l.Close()
+	l.SetCtrlCAborts(true)
+	res, err := l.PasswordPrompt(p)
+	if errors.Is(err, liner.ErrPromptAborted) {
+		return "", errors.New("password prompt aborted")
+	}
+	return res, err
+}
+
+// DefaultHistoryPath returns the path of the history file in the user's config dir.
+func DefaultHistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyDirName, historyFileName), nil
+}
+
+// Close saves the history and restores the terminal.
+func (r *REPL) Close() error {
+	return r.close()
+}
+
+// Run reads statements until EOF (Ctrl-D) or a quit command, e.g., `\q`, and passes them to exec.
+//
+// A statement is complete once a line ends it with a semicolon that is not inside a string or a comment (see script.Scanner).
+// Statements typed on the same line, e.g., `select 1; select 2;`, are passed to exec one by one. Ctrl-C discards the statement being typed.
+// Meta-commands, e.g., `\aliases`, need no semicolon: a line starting with a backslash is a complete statement by itself.
+// exec errors are reported but do not stop the REPL.
+func (r *REPL) Run(exec func(string) error) error {
+	s := &statement{}
+	for {
+		p := prompt
+		if !s.isEmpty() {
+			p = continuePrompt
+		}
+		line, err := r.r.Prompt(p)
+		switch {
+		case errors.Is(err, liner.ErrPromptAborted):
+			s.reset()
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+		if s.isEmpty() {
+			if _, ok := quitCommands[strings.TrimSpace(line)]; ok {
+				return nil
+			}
+		}
+		q, ok := s.add(line)
+		if !ok {
+			continue
+		}
+		r.r.AppendHistory(historyEntry(q))
+		for sc := script.NewScanner(strings.NewReader(q)); sc.Scan(); {
+			if err := exec(sc.Statement().Text); err != nil {
+				fmt.Fprintln(r.errw, err)
+			}
+		}
+	}
+}
+
+func (r *REPL) readHistory(l *liner.State) error {
+	if r.historyPath == "" {
+		return nil
+	}
+	f, err := os.Open(r.historyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read history: %w", err)
+	}
+	defer f.Close()
+	if _, err := l.ReadHistory(f); err != nil {
+		return fmt.Errorf("could not read history: %w", err)
+	}
+	return nil
+}
+
+func (r *REPL) writeHistory(l *liner.State) error {
+	if r.historyPath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.historyPath), 0o700); err != nil {
+		return fmt.Errorf("could not write history: %w", err)
+	}
+	f, err := os.OpenFile(r.historyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not write history: %w", err)
+	}
+	defer f.Close()
+	if _, err := l.WriteHistory(f); err != nil {
+		return fmt.Errorf("could not write history: %w", err)
+	}
+	return nil
+}
+
+// historyEntry turns a multi-line statement into a single line so that it can be recalled as a whole.
+func historyEntry(q string) string {
+	lines := strings.Split(q, "\n")
+	res := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			res = append(res, line)
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+// statement accumulates lines until the statement is terminated.
+type statement struct {
+	lines []string
+}
+
+// add adds line to the statement and, if the line terminates it, returns the complete statement and resets s.
+func (s *statement) add(line string) (string, bool) {
+	if s.isEmpty() {
+		switch trimmed := strings.TrimSpace(line); {
+		case trimmed == "":
+			return "", false
+		case strings.HasPrefix(trimmed, commandPrefix):
+			return trimmed, true
+		}
+	}
+	s.lines = append(s.lines, line)
+	res := strings.Join(s.lines, "\n")
+	if !script.Complete(res) {
+		return "", false
+	}
+	s.reset()
+	return res, true
+}
+
+func (s *statement) isEmpty() bool {
+	return len(s.lines) == 0
+}
+
+func (s *statement) reset() {
+	s.lines = nil
+}
@@ -0,0 +1,238 @@
+package repl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/eq"
+	"github.com/peterh/liner"
+)
+
+// testLineReader returns its lines one by one, and then io.EOF.
+type testLineReader struct {
+	lines   []interface{} // string or error
+	prompts []string
+	history []string
+}
+
+func (r *testLineReader) Prompt(p string) (string, error) {
+	r.prompts = append(r.prompts, p)
+	if len(r.lines) == 0 {
+		return "", io.EOF
+	}
+	line := r.lines[0]
+	r.lines = r.lines[1:]
+	if err, ok := line.(error); ok {
+		return "", err
+	}
+	return line.(string), nil
+}
+
+func (r *testLineReader) AppendHistory(item string) {
+	r.history = append(r.history, item)
+}
+
+func TestREPLRun(t *testing.T) {
+	errExec := errors.New("exec error")
+	errRead := errors.New("read error")
+	tests := []struct {
+		desc              string
+		lines             []interface{}
+		execErr           error
+		expectedStmts     []string
+		expectedHistory   []string
+		expectedPrompts   []string
+		expectedErrOutput string
+		err               bool
+	}{
+		{
+			desc:            "no input",
+			expectedPrompts: []string{prompt},
+		},
+		{
+			desc:            "single line",
+			lines:           []interface{}{"select 1;"},
+			expectedStmts:   []string{"select 1;"},
+			expectedHistory: []string{"select 1;"},
+			expectedPrompts: []string{prompt, prompt},
+		},
+		{
+			desc:            "multiple lines",
+			lines:           []interface{}{"select *", "  from foo", "  where id = 1 ;  ", "select 2;"},
+			expectedStmts:   []string{"select *\n  from foo\n  where id = 1 ;", "select 2;"},
+			expectedHistory: []string{"select * from foo where id = 1 ;", "select 2;"},
+			expectedPrompts: []string{prompt, continuePrompt, continuePrompt, prompt, prompt},
+		},
+		{
+			desc:            "several statements on one line",
+			lines:           []interface{}{"select 1; select 'a;b';  \\aliases"},
+			expectedStmts:   []string{"select 1;", "select 'a;b';", `\aliases`},
+			expectedHistory: []string{`select 1; select 'a;b';  \aliases`},
+			expectedPrompts: []string{prompt, prompt},
+		},
+		{
+			desc:            "statement ending on the line of the next one",
+			lines:           []interface{}{"select", "1; select 2;"},
+			expectedStmts:   []string{"select\n1;", "select 2;"},
+			expectedHistory: []string{"select 1; select 2;"},
+			expectedPrompts: []string{prompt, continuePrompt, prompt},
+		},
+		{
+			desc:              "exec error on one line",
+			lines:             []interface{}{"select 1; select 2;"},
+			execErr:           errExec,
+			expectedStmts:     []string{"select 1;", "select 2;"},
+			expectedHistory:   []string{"select 1; select 2;"},
+			expectedPrompts:   []string{prompt, prompt},
+			expectedErrOutput: fmt.Sprintf("%[1]s\n%[1]s\n", errExec),
+		},
+		{
+			desc:            "empty lines",
+			lines:           []interface{}{"", "  ", "select", "", "1;"},
+			expectedStmts:   []string{"select\n\n1;"},
+			expectedHistory: []string{"select 1;"},
+			expectedPrompts: []string{prompt, prompt, prompt, continuePrompt, continuePrompt, prompt},
+		},
+		{
+			desc:            "unterminated",
+			lines:           []interface{}{"select 1"},
+			expectedPrompts: []string{prompt, continuePrompt},
+		},
+		{
+			desc:            "semicolon in a string",
+			lines:           []interface{}{"select 'a;", "b';"},
+			expectedStmts:   []string{"select 'a;\nb';"},
+			expectedHistory: []string{"select 'a; b';"},
+			expectedPrompts: []string{prompt, continuePrompt, prompt},
+		},
+		{
+			desc:            "semicolon in a comment",
+			lines:           []interface{}{"select 1 -- note;", "+ 1;"},
+			expectedStmts:   []string{"select 1 -- note;\n+ 1;"},
+			expectedHistory: []string{"select 1 -- note; + 1;"},
+			expectedPrompts: []string{prompt, continuePrompt, prompt},
+		},
+		{
+			desc:            "Ctrl-C discards the statement",
+			lines:           []interface{}{"select", liner.ErrPromptAborted, "select 2;"},
+			expectedStmts:   []string{"select 2;"},
+			expectedHistory: []string{"select 2;"},
+			expectedPrompts: []string{prompt, continuePrompt, prompt, prompt},
+		},
+		{
+			desc:            "quit",
+			lines:           []interface{}{"select 1;", ` \q `, "select 2;"},
+			expectedStmts:   []string{"select 1;"},
+			expectedHistory: []string{"select 1;"},
+			expectedPrompts: []string{prompt, prompt},
+		},
+		{
+			desc:            "quit is not a command within a statement",
+			lines:           []interface{}{"select", "exit;"},
+			expectedStmts:   []string{"select\nexit;"},
+			expectedHistory: []string{"select exit;"},
+			expectedPrompts: []string{prompt, continuePrompt, prompt},
+		},
+		{
+			desc:            "meta-commands",
+			lines:           []interface{}{` \aliases `, `\describe foo.Bar;`, "select 1;"},
+			expectedStmts:   []string{`\aliases`, `\describe foo.Bar;`, "select 1;"},
+			expectedHistory: []string{`\aliases`, `\describe foo.Bar;`, "select 1;"},
+			expectedPrompts: []string{prompt, prompt, prompt, prompt},
+		},
+		{
+			desc:            "meta-command within a statement",
+			lines:           []interface{}{"select", `\aliases;`},
+			expectedStmts:   []string{"select\n\\aliases;"},
+			expectedHistory: []string{`select \aliases;`},
+			expectedPrompts: []string{prompt, continuePrompt, prompt},
+		},
+		{
+			desc:              "exec error",
+			lines:             []interface{}{"select 1;", "select 2;"},
+			execErr:           errExec,
+			expectedStmts:     []string{"select 1;", "select 2;"},
+			expectedHistory:   []string{"select 1;", "select 2;"},
+			expectedPrompts:   []string{prompt, prompt, prompt},
+			expectedErrOutput: fmt.Sprintf("%[1]s\n%[1]s\n", errExec),
+		},
+		{
+			desc:            "read error",
+			lines:           []interface{}{"select 1;", errRead},
+			expectedStmts:   []string{"select 1;"},
+			expectedHistory: []string{"select 1;"},
+			expectedPrompts: []string{prompt, prompt},
+			err:             true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			lr := &testLineReader{lines: test.lines}
+			errw := &bytes.Buffer{}
+			r := &REPL{r: lr, errw: errw}
+			var stmts []string
+			err := r.Run(func(q string) error {
+				stmts = append(stmts, q)
+				return test.execErr
+			})
+			testcheck.FatalIfUnexpected(t, err, test.err)
+			if !eq.StringSlices(stmts, test.expectedStmts) {
+				t.Fatalf("expected statements to be %q but they were %q", test.expectedStmts, stmts)
+			}
+			if !eq.StringSlices(lr.history, test.expectedHistory) {
+				t.Fatalf("expected history to be %q but it was %q", test.expectedHistory, lr.history)
+			}
+			if !eq.StringSlices(lr.prompts, test.expectedPrompts) {
+				t.Fatalf("expected prompts to be %q but they were %q", test.expectedPrompts, lr.prompts)
+			}
+			if errOutput := errw.String(); errOutput != test.expectedErrOutput {
+				t.Fatalf("expected error output to be %q but it was %q", test.expectedErrOutput, errOutput)
+			}
+		})
+	}
+}
+
+func TestDefaultHistoryPath(t *testing.T) {
+	res, err := DefaultHistoryPath()
+	if err != nil {
+		t.Skipf("no user config dir: %v", err)
+	}
+	if !strings.HasSuffix(res, historyFileName) {
+		t.Fatalf("expected %q to end with %q but it did not", res, historyFileName)
+	}
+}
+
+func TestREPLHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), historyDirName, historyFileName)
+	r, err := New(path, io.Discard, nil)
+	testcheck.FatalIf(t, err)
+	r.r.AppendHistory("select 1;")
+	r.r.AppendHistory("select 2;")
+	testcheck.FatalIf(t, r.Close())
+
+	b, err := os.ReadFile(path)
+	testcheck.FatalIf(t, err)
+	if expected := "select 1;\nselect 2;\n"; string(b) != expected {
+		t.Fatalf("expected history to be %q but it was %q", expected, b)
+	}
+
+	r, err = New(path, io.Discard, nil) // reads the history back
+	testcheck.FatalIf(t, err)
+	r.r.AppendHistory("select 3;")
+	testcheck.FatalIf(t, r.Close())
+
+	b, err = os.ReadFile(path)
+	testcheck.FatalIf(t, err)
+	if expected := "select 1;\nselect 2;\nselect 3;\n"; string(b) != expected {
+		t.Fatalf("expected history to be %q but it was %q", expected, b)
+	}
+}
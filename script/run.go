@@ -0,0 +1,40 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Run runs the statements read from r with exec, stopping at the first one that fails unless keepGoing is true.
+//
+// Errors are annotated with the line the failing statement starts on. If keepGoing is true, they are written to errw
+// as they occur, and the returned error summarizes them. Run always stops once ctx is done.
+func Run(ctx context.Context, r io.Reader, exec func(string) error, keepGoing bool, errw io.Writer) error {
+	s := NewScanner(r)
+	count := 0
+	failed := []string{}
+	for s.Scan() {
+		stmt := s.Statement()
+		count++
+		err := exec(stmt.Text)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("line %d: %w", stmt.Line, err)
+		if !keepGoing || ctx.Err() != nil {
+			return err
+		}
+		fmt.Fprintln(errw, err)
+		failed = append(failed, strconv.Itoa(stmt.Line))
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d statements failed (lines %s)", len(failed), count, strings.Join(failed, ", "))
+	}
+	return nil
+}
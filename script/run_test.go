@@ -0,0 +1,85 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/m18/cpb/internal/testcheck"
+	"github.com/m18/eq"
+)
+
+func TestRun(t *testing.T) {
+	errExec := errors.New("exec error")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		desc              string
+		ctx               context.Context
+		script            string
+		keepGoing         bool
+		expectedStmts     []string
+		expectedErr       string
+		expectedErrOutput string
+	}{
+		{
+			desc:          "no errors",
+			script:        "select 1;\nselect 2;",
+			expectedStmts: []string{"select 1;", "select 2;"},
+		},
+		{
+			desc:          "stop",
+			script:        "select 1;\nfail;\nselect 2;",
+			expectedStmts: []string{"select 1;", "fail;"},
+			expectedErr:   "line 2: exec error",
+		},
+		{
+			desc:              "continue",
+			script:            "select 1;\nfail;\nselect 2;\n\nfail\n;",
+			keepGoing:         true,
+			expectedStmts:     []string{"select 1;", "fail;", "select 2;", "fail\n;"},
+			expectedErr:       "2 of 4 statements failed (lines 2, 5)",
+			expectedErrOutput: "line 2: exec error\nline 5: exec error\n",
+		},
+		{
+			desc:          "continue, ctx done",
+			ctx:           canceled,
+			script:        "fail;\nselect 1;",
+			keepGoing:     true,
+			expectedStmts: []string{"fail;"},
+			expectedErr:   "line 1: exec error",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			ctx := test.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			var stmts []string
+			exec := func(q string) error {
+				stmts = append(stmts, q)
+				if strings.HasPrefix(q, "fail") {
+					return errExec
+				}
+				return nil
+			}
+			errw := &bytes.Buffer{}
+			err := Run(ctx, strings.NewReader(test.script), exec, test.keepGoing, errw)
+			testcheck.FatalIfUnexpected(t, err, test.expectedErr != "")
+			if err != nil && err.Error() != test.expectedErr {
+				t.Fatalf("expected error to be %q but it was %q", test.expectedErr, err)
+			}
+			if !eq.StringSlices(stmts, test.expectedStmts) {
+				t.Fatalf("expected statements to be %q but they were %q", test.expectedStmts, stmts)
+			}
+			if errOutput := errw.String(); errOutput != test.expectedErrOutput {
+				t.Fatalf("expected error output to be %q but it was %q", test.expectedErrOutput, errOutput)
+			}
+		})
+	}
+}
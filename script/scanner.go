@@ -0,0 +1,226 @@
+package script
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+)
+
+const (
+	terminator    = ';'
+	commandPrefix = '\\'
+)
+
+// Statement is a statement read from a script.
+type Statement struct {
+	Text string
+	Line int // the line the statement starts on, 1-based
+}
+
+// Scanner splits a script into statements.
+//
+// Statements end with a semicolon that is not inside a single-quoted string (where \' is an escaped quote),
+// a double- or backtick-quoted identifier, a dollar-quoted string, e.g., $$...$$ or $tag$...$tag$, or a -- or /* */ comment.
+// Meta-commands, e.g., `\aliases`, end with the line they are on. The last statement does not need to be terminated.
+//
+// Comments and whitespace preceding a statement are dropped. Lines are not limited in length.
+type Scanner struct {
+	r          *bufio.Reader
+	line       int
+	stmt       *Statement
+	terminated bool // whether stmt ended with a semicolon, or is a meta-command
+	unclosed   bool // whether a /* */ comment was still open at EOF
+	err        error
+}
+
+// NewScanner returns a new Scanner reading from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:    bufio.NewReader(r),
+		line: 1,
+	}
+}
+
+// Scan advances the Scanner to the next statement, which is then available through Statement.
+// It returns false when there are no more statements, either because of EOF or an error, which Err then returns.
+func (s *Scanner) Scan() bool {
+	s.stmt = nil
+	if s.err != nil {
+		return false
+	}
+	var sb strings.Builder
+	start := 0 // the line of the first rune of the statement, 0 until there is one
+	for {
+		c, ok := s.next()
+		if !ok {
+			break
+		}
+		switch {
+		case c == '-' && s.peek() == '-':
+			s.next()
+			s.comment(&sb, start > 0, "--", "\n")
+			continue
+		case c == '/' && s.peek() == '*':
+			s.next()
+			s.comment(&sb, start > 0, "/*", "*/")
+			continue
+		case start > 0:
+		case unicode.IsSpace(c):
+			continue
+		case c == commandPrefix:
+			start = s.line
+			sb.WriteRune(c)
+			s.command(&sb)
+			return s.emit(&sb, start, true)
+		default:
+			start = s.line
+		}
+		sb.WriteRune(c)
+		switch c {
+		case terminator:
+			return s.emit(&sb, start, true)
+		case '\'':
+			s.quoted(&sb, c, true)
+		case '"', '`':
+			s.quoted(&sb, c, false)
+		case '$':
+			s.dollarQuoted(&sb)
+		}
+	}
+	if s.err != nil || start == 0 {
+		return false
+	}
+	return s.emit(&sb, start, false)
+}
+
+// Statement returns the statement found by the last call to Scan.
+func (s *Scanner) Statement() *Statement {
+	return s.stmt
+}
+
+// Err returns the first non-EOF error encountered by the Scanner.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+func (s *Scanner) emit(sb *strings.Builder, start int, terminated bool) bool {
+	s.stmt = &Statement{
+		Text: strings.TrimSpace(sb.String()),
+		Line: start,
+	}
+	s.terminated = terminated
+	return true
+}
+
+// Complete reports whether q is ready to be run, i.e., whether its last statement ends with a semicolon
+// (see Scanner for where one does not end a statement) or is a meta-command, and no /* */ comment is left open after it.
+func Complete(q string) bool {
+	s := NewScanner(strings.NewReader(q))
+	found := false
+	for s.Scan() {
+		found = true
+	}
+	return found && s.terminated && !s.unclosed
+}
+
+// next reads the next rune. It returns false on EOF or an error.
+func (s *Scanner) next() (rune, bool) {
+	c, _, err := s.r.ReadRune()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.err = err
+		}
+		return 0, false
+	}
+	if c == '\n' {
+		s.line++
+	}
+	return c, true
+}
+
+// peek returns the next rune without reading it, or 0 if there is none.
+func (s *Scanner) peek() rune {
+	c, _, err := s.r.ReadRune()
+	if err != nil {
+		return 0
+	}
+	s.r.UnreadRune()
+	return c
+}
+
+// comment reads a comment up to and including end, writing it to sb if keep is true.
+func (s *Scanner) comment(sb *strings.Builder, keep bool, begin, end string) {
+	var body strings.Builder
+	body.WriteString(begin)
+	for !strings.HasSuffix(body.String(), end) {
+		c, ok := s.next()
+		if !ok {
+			// a -- comment ends with the input as much as with a line break
+			s.unclosed = end != "\n"
+			break
+		}
+		body.WriteRune(c)
+	}
+	if keep {
+		sb.WriteString(body.String())
+	}
+}
+
+// command reads the rest of a meta-command line.
+func (s *Scanner) command(sb *strings.Builder) {
+	for {
+		c, ok := s.next()
+		if !ok || c == '\n' {
+			return
+		}
+		sb.WriteRune(c)
+	}
+}
+
+// quoted reads the rest of a string or an identifier quoted with q, optionally with backslash escapes, e.g., \'.
+func (s *Scanner) quoted(sb *strings.Builder, q rune, backslash bool) {
+	for {
+		c, ok := s.next()
+		if !ok {
+			return
+		}
+		sb.WriteRune(c)
+		switch {
+		case c == q:
+			return
+		case c == '\\' && backslash:
+			if c, ok = s.next(); !ok {
+				return
+			}
+			sb.WriteRune(c)
+		}
+	}
+}
+
+// dollarQuoted reads the rest of a dollar-quoted string, e.g., $$...$$ or $tag$...$tag$.
+// If the $ does not open one, e.g., in `$1` or `$e(`, it only reads what could have been the tag.
+func (s *Scanner) dollarQuoted(sb *strings.Builder) {
+	var tag strings.Builder
+	for c := s.peek(); c == '_' || unicode.IsLetter(c) || (unicode.IsDigit(c) && tag.Len() > 0); c = s.peek() {
+		s.next()
+		sb.WriteRune(c)
+		tag.WriteRune(c)
+	}
+	if s.peek() != '$' {
+		return
+	}
+	s.next()
+	sb.WriteRune('$')
+	delim := "$" + tag.String() + "$"
+	var body strings.Builder
+	for !strings.HasSuffix(body.String(), delim) {
+		c, ok := s.next()
+		if !ok {
+			return
+		}
+		sb.WriteRune(c)
+		body.WriteRune(c)
+	}
+}
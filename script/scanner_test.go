@@ -0,0 +1,185 @@
+package script
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/m18/cpb/internal/testcheck"
+)
+
+func TestScannerScan(t *testing.T) {
+	tests := []struct {
+		desc     string
+		script   string
+		expected []Statement
+	}{
+		{
+			desc: "empty",
+		},
+		{
+			desc:   "whitespace and comments only",
+			script: "  \n-- foo;\n/* bar; */\n",
+		},
+		{
+			desc:   "one per line",
+			script: "select 1;\nselect 2;\n",
+			expected: []Statement{
+				{Text: "select 1;", Line: 1},
+				{Text: "select 2;", Line: 2},
+			},
+		},
+		{
+			desc:   "several per line",
+			script: "select 1; select 2;select 3;",
+			expected: []Statement{
+				{Text: "select 1;", Line: 1},
+				{Text: "select 2;", Line: 1},
+				{Text: "select 3;", Line: 1},
+			},
+		},
+		{
+			desc:   "multiple lines",
+			script: "\n\nselect *\n  from foo\n  where id = 1;\n\nselect 2;",
+			expected: []Statement{
+				{Text: "select *\n  from foo\n  where id = 1;", Line: 3},
+				{Text: "select 2;", Line: 7},
+			},
+		},
+		{
+			desc:   "unterminated last statement",
+			script: "select 1;\nselect 2\n",
+			expected: []Statement{
+				{Text: "select 1;", Line: 1},
+				{Text: "select 2", Line: 2},
+			},
+		},
+		{
+			desc:   "single-quoted strings",
+			script: `insert into foo values ('a;b', 'O\'Reilly;', 'it''s;');` + "\nselect 2;",
+			expected: []Statement{
+				{Text: `insert into foo values ('a;b', 'O\'Reilly;', 'it''s;');`, Line: 1},
+				{Text: "select 2;", Line: 2},
+			},
+		},
+		{
+			desc:   "in-message args",
+			script: `select * from foo where bar = $e('a;b', 1); select $e:"the;details" from foo;`,
+			expected: []Statement{
+				{Text: `select * from foo where bar = $e('a;b', 1);`, Line: 1},
+				{Text: `select $e:"the;details" from foo;`, Line: 1},
+			},
+		},
+		{
+			desc:   "quoted identifiers",
+			script: "select \"a;b\", `c;d` from foo;",
+			expected: []Statement{
+				{Text: "select \"a;b\", `c;d` from foo;", Line: 1},
+			},
+		},
+		{
+			desc:   "dollar-quoted strings",
+			script: "create function f() returns int as $$\nselect 1;\n$$ language sql;\nselect $tag$ $$; $tag$, $1;",
+			expected: []Statement{
+				{Text: "create function f() returns int as $$\nselect 1;\n$$ language sql;", Line: 1},
+				{Text: "select $tag$ $$; $tag$, $1;", Line: 4},
+			},
+		},
+		{
+			desc:   "comments",
+			script: "-- leading;\nselect 1, -- one;\n  2 /* two;\n */;\n/* trailing; */",
+			expected: []Statement{
+				{Text: "select 1, -- one;\n  2 /* two;\n */;", Line: 2},
+			},
+		},
+		{
+			desc:   "meta-commands",
+			script: "\\aliases\n  \\describe foo.Bar;\nselect 1\n\\config;",
+			expected: []Statement{
+				{Text: `\aliases`, Line: 1},
+				{Text: `\describe foo.Bar;`, Line: 2},
+				{Text: "select 1\n\\config;", Line: 3},
+			},
+		},
+		{
+			desc:   "long lines",
+			script: "select '" + strings.Repeat("a", 100*1024) + "';",
+			expected: []Statement{
+				{Text: "select '" + strings.Repeat("a", 100*1024) + "';", Line: 1},
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			s := NewScanner(strings.NewReader(test.script))
+			res := []Statement{}
+			for s.Scan() {
+				res = append(res, *s.Statement())
+			}
+			testcheck.FatalIf(t, s.Err())
+			if len(res) != len(test.expected) {
+				t.Fatalf("expected %d statements but got %d: %q", len(test.expected), len(res), res)
+			}
+			for i, stmt := range res {
+				if stmt != test.expected[i] {
+					t.Fatalf("expected statement %d to be %q but it was %q", i, test.expected[i], stmt)
+				}
+			}
+		})
+	}
+}
+
+func TestScannerScanErr(t *testing.T) {
+	errRead := errors.New("read error")
+	s := NewScanner(io.MultiReader(strings.NewReader("select 1; select"), iotest.ErrReader(errRead)))
+	if !s.Scan() {
+		t.Fatalf("expected a statement but got none")
+	}
+	if s.Scan() {
+		t.Fatalf("expected no statement but got %q", s.Statement().Text)
+	}
+	if !errors.Is(s.Err(), errRead) {
+		t.Fatalf("expected error to be %v but it was %v", errRead, s.Err())
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		q        string
+		expected bool
+	}{
+		{q: "", expected: false},
+		{q: "  \n", expected: false},
+		{q: "select 1", expected: false},
+		{q: "select 1;", expected: true},
+		{q: "select 1;  ", expected: true},
+		{q: "select 1; select", expected: false},
+		{q: "select 1; select 2;", expected: true},
+		{q: "select 'a;", expected: false},
+		{q: "select 'a;\nb';", expected: true},
+		{q: `select 'it\'s;`, expected: false},
+		{q: `select "a;`, expected: false},
+		{q: "select $$a;", expected: false},
+		{q: "select $$a;$$;", expected: true},
+		{q: "select 1 -- note;", expected: false},
+		{q: "select 1 /* note; */", expected: false},
+		{q: "-- note;", expected: false},
+		{q: "select 1; -- note", expected: true},
+		{q: "select 1; /* note", expected: false},
+		{q: "select 1; /* note */", expected: true},
+		{q: `\aliases`, expected: true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.q, func(t *testing.T) {
+			t.Parallel()
+			if res := Complete(test.q); res != test.expected {
+				t.Fatalf("expected %t but got %t", test.expected, res)
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package sys
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+
+	"github.com/mattn/go-isatty"
 )
 
 // IsPipedIn detects whether data is being piped-in from another command or not.
@@ -17,13 +20,19 @@ func IsPipedIn() (bool, error) {
 	return piped, nil
 }
 
-// ExitIf prints err and exits with an error code if err is not nil, and is not the same as one of the except values.
+// IsTerminal detects whether stdin is a terminal, e.g., that a password can be prompted for.
+func IsTerminal() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// ExitIf prints err and exits with an error code if err is not nil, and does not wrap one of the except values.
 func ExitIf(err error, except ...error) {
 	if err == nil {
 		return
 	}
 	for _, e := range except {
-		if err == e {
+		if e != nil && errors.Is(err, e) {
 			return
 		}
 	}
@@ -36,6 +45,7 @@ func HandleInterrupt(ctx context.Context, cb func()) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill)
 	go func() {
+		defer signal.Stop(c)
 		select {
 		case <-c:
 			cb()